@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cherserver/infocenter/service/devices/xiaomi/transport"
+)
+
+func runDiscover(args []string) {
+	flags := flag.NewFlagSet("infocenter "+commandDiscover, flag.ExitOnError)
+	timeout := flags.Duration("timeout", 3*time.Second, "how long to wait for gateway answers")
+	_ = flags.Parse(args)
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), *timeout)
+	defer cancelFunc()
+
+	gateways, err := transport.Discover(ctx)
+	if err != nil {
+		log.Fatalf("Failed to discover gateways: %v", err)
+	}
+
+	if len(gateways) == 0 {
+		fmt.Println("No gateways found")
+		return
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(writer, "SID\tADDRESS\tPORT\tMODEL\tPROTOCOL")
+	for _, gateway := range gateways {
+		_, _ = fmt.Fprintf(writer, "%s\t%s\t%d\t%s\t%s\n",
+			gateway.SID, gateway.Address, gateway.Port, gateway.Model, gateway.ProtoVersion)
+	}
+
+	_ = writer.Flush()
+}
@@ -1,39 +1,140 @@
 package main
 
 import (
+	"flag"
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 
+	"github.com/cherserver/infocenter/service/calibration"
+	"github.com/cherserver/infocenter/service/config"
+	"github.com/cherserver/infocenter/service/devices"
 	"github.com/cherserver/infocenter/service/devices/xiaomi"
+	"github.com/cherserver/infocenter/service/devices/xiaomi/transport"
+	"github.com/cherserver/infocenter/service/history"
 	"github.com/cherserver/infocenter/service/weather"
 	"github.com/cherserver/infocenter/service/web"
 )
 
 const (
-	gatewayAddr  = "192.168.31.21"
-	gatewayToken = "540b4bf40bb290ef62004d27fc3438e6"
+	commandDiscover = "discover"
+	commandSimulate = "simulate"
 )
 
 func main() {
-	gateway, err := xiaomi.NewGateway(gatewayAddr, gatewayToken)
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case commandDiscover:
+			runDiscover(os.Args[2:])
+			return
+		case commandSimulate:
+			runSimulate(os.Args[2:])
+			return
+		}
+	}
+
+	runService(os.Args[1:])
+}
+
+func runService(args []string) {
+	flags := flag.NewFlagSet("infocenter", flag.ExitOnError)
+	configPath := flags.String("config", config.DefaultPath, "path to the configuration file")
+	_ = flags.Parse(args)
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	err = os.MkdirAll(cfg.Storage.Dir, 0o755)
+	if err != nil {
+		log.Fatalf("Failed to create storage directory: %v", err)
+	}
+
+	gateways := make([]*xiaomi.Gateway, 0, len(cfg.Gateways))
+	registry := devices.NewRegistry()
+
+	calibrations, err := calibration.Load(filepath.Join(cfg.Storage.Dir, "calibration.json"), registry)
 	if err != nil {
-		log.Fatalf("Failed to create gateway: %v", err)
+		log.Fatalf("Failed to load calibrations: %v", err)
 	}
 
-	err = gateway.Init()
+	historyStore := history.New(history.Config{
+		Dir:               filepath.Join(cfg.Storage.Dir, "history"),
+		RawRetention:      cfg.Storage.History.RawRetention,
+		AveragesRetention: cfg.Storage.History.AveragesRetention,
+	})
+	err = historyStore.Init()
 	if err != nil {
-		log.Fatalf("Failed to initialize gateway: %v", err)
+		log.Fatalf("Failed to initialize history: %v", err)
 	}
 
-	weatherSource := weather.New()
+	for _, gatewayCfg := range cfg.Gateways {
+		jitter := transport.DefaultRetryPolicy().Jitter
+		if gatewayCfg.Retry.Jitter != nil {
+			jitter = *gatewayCfg.Retry.Jitter
+		}
+
+		gateway, err := xiaomi.NewGateway(xiaomi.Config{
+			Transport: transport.Config{
+				Name:    gatewayCfg.Name,
+				Address: gatewayCfg.Address,
+				Port:    gatewayCfg.Port,
+				SID:     gatewayCfg.SID,
+				Token:   gatewayCfg.Token,
+
+				EventsAddress: gatewayCfg.EventsAddress,
+
+				RequestTimeout: gatewayCfg.RequestTimeout,
+				Retry: transport.RetryPolicy{
+					Attempts:   gatewayCfg.Retry.Attempts,
+					Backoff:    gatewayCfg.Retry.Backoff,
+					MaxBackoff: gatewayCfg.Retry.MaxBackoff,
+					Jitter:     jitter,
+				},
+			},
+			RescanInterval: gatewayCfg.RescanInterval,
+		})
+		if err != nil {
+			log.Fatalf("Failed to create gateway '%v': %v", gatewayCfg.Name, err)
+		}
+
+		registry.Attach(gateway)
+		gateway.RegisterChangeConsumer(calibrations.OnChange)
+		gateway.RegisterReadingConsumer(historyStore.Record)
+
+		err = gateway.Init()
+		if err != nil {
+			log.Fatalf("Failed to initialize gateway '%v': %v", gatewayCfg.Name, err)
+		}
+
+		gateways = append(gateways, gateway)
+	}
+
+	weatherSource := weather.New(cfg.Weather.APIKey, cfg.Weather.Latitude, cfg.Weather.Longitude)
+	weatherSource.RegisterReadingConsumer(historyStore.Record)
 	err = weatherSource.Init()
 	if err != nil {
 		log.Fatalf("Failed to initialize weather: %v", err)
 	}
 
-	webServer := web.NewServer(gateway, weatherSource)
+	localWeather := weather.NewZambretti(registry, historyStore, cfg.Weather.Barometer, cfg.Weather.Altitude)
+
+	sensorsMeta := make(map[string]web.SensorMeta, len(cfg.Sensors))
+	for _, sensorCfg := range cfg.Sensors {
+		sensorsMeta[sensorCfg.SID] = web.SensorMeta{
+			Name:   sensorCfg.Name,
+			Room:   sensorCfg.Room,
+			Icon:   sensorCfg.Icon,
+			Order:  sensorCfg.Order,
+			Hidden: sensorCfg.Hidden,
+		}
+	}
+
+	webServer := web.NewServer(cfg.Web.Listen, cfg.Web.Root, registry, sensorsMeta, calibrations,
+		historyStore, weatherSource, localWeather, cfg.Weather.Altitude)
 	err = webServer.Init()
 	if err != nil {
 		log.Fatalf("Failed to initialize web server: %v", err)
@@ -46,5 +147,8 @@ func main() {
 
 	webServer.Stop()
 	weatherSource.Stop()
-	gateway.Stop()
+	for _, gateway := range gateways {
+		gateway.Stop()
+	}
+	historyStore.Stop()
 }
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/cherserver/infocenter/service/devices/xiaomi/simulator"
+)
+
+// defaultSimulatedDevices mirror the sensors of the original installation.
+var defaultSimulatedDevices = []simulator.Device{
+	{
+		SID:   "158d0001fd4989",
+		Model: "sensor_ht",
+		Data:  map[string]interface{}{"voltage": 3005, "temperature": "2150", "humidity": "4520"},
+	},
+	{
+		SID:   "158d000247d48b",
+		Model: "weather.v1",
+		Data:  map[string]interface{}{"voltage": 3015, "temperature": "2230", "humidity": "4010", "pressure": "101325"},
+	},
+	{
+		SID:   "158d0001f57fee",
+		Model: "sensor_ht",
+		Data:  map[string]interface{}{"voltage": 2955, "temperature": "1270", "humidity": "6830"},
+	},
+}
+
+func runSimulate(args []string) {
+	flags := flag.NewFlagSet("infocenter "+commandSimulate, flag.ExitOnError)
+	configPath := flags.String("config", "", "path to the simulator configuration file")
+	address := flags.String("listen", "", "address to listen for gateway requests on")
+	eventsAddress := flags.String("events", "", "address to send heartbeat and report events to")
+	_ = flags.Parse(args)
+
+	cfg := simulator.Config{Drift: true}
+	if *configPath != "" {
+		data, err := os.ReadFile(*configPath)
+		if err != nil {
+			log.Fatalf("Failed to read simulator configuration: %v", err)
+		}
+
+		err = yaml.Unmarshal(data, &cfg)
+		if err != nil {
+			log.Fatalf("Failed to parse simulator configuration: %v", err)
+		}
+	}
+
+	if len(cfg.Devices) == 0 {
+		cfg.Devices = defaultSimulatedDevices
+	}
+
+	if *address != "" {
+		cfg.Address = *address
+	}
+
+	if *eventsAddress != "" {
+		cfg.EventsAddress = *eventsAddress
+	}
+
+	sim, err := simulator.New(cfg)
+	if err != nil {
+		log.Fatalf("Failed to create simulator: %v", err)
+	}
+
+	err = sim.Start()
+	if err != nil {
+		log.Fatalf("Failed to start simulator: %v", err)
+	}
+
+	stopSignalCh := make(chan os.Signal, 1)
+	signal.Notify(stopSignalCh, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM)
+	stopSignal := <-stopSignalCh
+	log.Printf("Signal '%+v' caught, exit", stopSignal)
+
+	sim.Stop()
+}
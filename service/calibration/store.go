@@ -0,0 +1,130 @@
+package calibration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/cherserver/infocenter/service/devices"
+)
+
+// Store keeps the device calibrations by SID in a JSON file and applies them to the devices.
+type Store struct {
+	path string
+
+	registry *devices.Registry
+
+	calibrations map[string]devices.Calibration
+	mutex        sync.Mutex
+}
+
+// Load reads the calibrations file, a missing file means no calibrations yet.
+func Load(path string, registry *devices.Registry) (*Store, error) {
+	store := &Store{
+		path:         path,
+		registry:     registry,
+		calibrations: make(map[string]devices.Calibration),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read calibrations: %w", err)
+	}
+
+	err = json.Unmarshal(data, &store.calibrations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse calibrations '%v': %w", path, err)
+	}
+
+	return store, nil
+}
+
+// OnChange applies the stored calibration to the devices being added.
+func (s *Store) OnChange(change devices.Change) {
+	if change.Kind != devices.DeviceAdded {
+		return
+	}
+
+	calibratable, ok := change.Device.(devices.Calibratable)
+	if !ok {
+		return
+	}
+
+	if calibration, fnd := s.Get(change.SID); fnd {
+		calibratable.SetCalibration(calibration)
+		log.Printf("Device '%v' calibration applied: %+v", change.SID, calibration)
+	}
+}
+
+func (s *Store) Get(sid string) (devices.Calibration, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	calibration, fnd := s.calibrations[sid]
+	return calibration, fnd
+}
+
+// Set stores the calibration and applies it to the device if it is registered, zero calibration removes it.
+func (s *Store) Set(sid string, calibration devices.Calibration) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	previous, hadPrevious := s.calibrations[sid]
+	if calibration == (devices.Calibration{}) {
+		delete(s.calibrations, sid)
+	} else {
+		s.calibrations[sid] = calibration
+	}
+
+	err := s.save()
+	if err != nil {
+		if hadPrevious {
+			s.calibrations[sid] = previous
+		} else {
+			delete(s.calibrations, sid)
+		}
+
+		return err
+	}
+
+	if calibratable, ok := devices.Lookup[devices.Calibratable](s.registry, sid); ok {
+		calibratable.SetCalibration(calibration)
+	}
+
+	log.Printf("Device '%v' calibration set: %+v", sid, calibration)
+
+	return nil
+}
+
+// save writes the calibrations to a temporary file renamed over the previous one, so a crash never leaves it partial.
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(s.calibrations, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode calibrations: %w", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(s.path), 0o755)
+	if err != nil {
+		return fmt.Errorf("failed to create calibrations directory: %w", err)
+	}
+
+	tmpPath := s.path + ".tmp"
+	err = os.WriteFile(tmpPath, data, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to write calibrations: %w", err)
+	}
+
+	err = os.Rename(tmpPath, s.path)
+	if err != nil {
+		return fmt.Errorf("failed to replace calibrations: %w", err)
+	}
+
+	return nil
+}
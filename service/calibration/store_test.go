@@ -0,0 +1,134 @@
+package calibration
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cherserver/infocenter/service/devices"
+)
+
+type testThermometer struct {
+	sid         string
+	calibration devices.Calibration
+}
+
+func (d *testThermometer) SID() string             { return d.sid }
+func (d *testThermometer) Model() string           { return "sensor_ht" }
+func (d *testThermometer) LastUpdateAt() time.Time { return time.Now() }
+func (d *testThermometer) Temperature() float32    { return d.calibration.Temperature(21.5) }
+
+func (d *testThermometer) Calibration() devices.Calibration     { return d.calibration }
+func (d *testThermometer) SetCalibration(c devices.Calibration) { d.calibration = c }
+
+func (d *testThermometer) RawMeasurements() devices.Measurements {
+	raw := float32(21.5)
+	return devices.Measurements{Temperature: &raw}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calibration.json")
+
+	store, err := Load(path, devices.NewRegistry())
+	require.NoError(t, err)
+	_, fnd := store.Get("1")
+	require.False(t, fnd)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"1":{"temperature_offset":-0.5}}`), 0o644))
+	store, err = Load(path, devices.NewRegistry())
+	require.NoError(t, err)
+	calibration, fnd := store.Get("1")
+	require.True(t, fnd)
+	require.Equal(t, devices.Calibration{TemperatureOffset: -0.5}, calibration)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"1":{"temperature_offset":`), 0o644))
+	_, err = Load(path, devices.NewRegistry())
+	require.ErrorContains(t, err, "failed to parse calibrations")
+}
+
+func TestSet(t *testing.T) {
+	registry := devices.NewRegistry()
+	device := &testThermometer{sid: "1"}
+	registry.Add(device)
+
+	path := filepath.Join(t.TempDir(), "data", "calibration.json")
+	store, err := Load(path, registry)
+	require.NoError(t, err)
+
+	calibration := devices.Calibration{TemperatureOffset: 1, HumidityScale: 1.1}
+	require.NoError(t, store.Set("1", calibration))
+	require.Equal(t, calibration, device.Calibration())
+	require.InDelta(t, 22.5, device.Temperature(), 0.0001)
+
+	// calibrations of devices not registered yet are kept too
+	require.NoError(t, store.Set("2", devices.Calibration{PressureOffset: 120}))
+
+	reloaded, err := Load(path, registry)
+	require.NoError(t, err)
+	stored, fnd := reloaded.Get("1")
+	require.True(t, fnd)
+	require.Equal(t, calibration, stored)
+	_, fnd = reloaded.Get("2")
+	require.True(t, fnd)
+
+	require.NoError(t, store.Set("1", devices.Calibration{}))
+	require.Zero(t, device.Calibration())
+	_, fnd = store.Get("1")
+	require.False(t, fnd)
+
+	reloaded, err = Load(path, registry)
+	require.NoError(t, err)
+	_, fnd = reloaded.Get("1")
+	require.False(t, fnd)
+}
+
+func TestSetRollback(t *testing.T) {
+	registry := devices.NewRegistry()
+	device := &testThermometer{sid: "1"}
+	registry.Add(device)
+
+	path := filepath.Join(t.TempDir(), "calibration.json")
+	store, err := Load(path, registry)
+	require.NoError(t, err)
+
+	// the temporary file can't be written over a directory
+	require.NoError(t, os.Mkdir(path+".tmp", 0o755))
+
+	store.calibrations["1"] = devices.Calibration{TemperatureOffset: 1}
+	require.Error(t, store.Set("1", devices.Calibration{TemperatureOffset: 2}))
+	calibration, fnd := store.Get("1")
+	require.True(t, fnd)
+	require.Equal(t, devices.Calibration{TemperatureOffset: 1}, calibration)
+	require.Zero(t, device.Calibration())
+
+	require.Error(t, store.Set("1", devices.Calibration{}))
+	_, fnd = store.Get("1")
+	require.True(t, fnd)
+
+	require.Error(t, store.Set("2", devices.Calibration{TemperatureOffset: 2}))
+	_, fnd = store.Get("2")
+	require.False(t, fnd)
+}
+
+func TestOnChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calibration.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"1":{"temperature_offset":-0.5}}`), 0o644))
+
+	store, err := Load(path, devices.NewRegistry())
+	require.NoError(t, err)
+
+	device := &testThermometer{sid: "1"}
+	store.OnChange(devices.Change{Kind: devices.DeviceLivenessChanged, SID: "1", Device: device})
+	require.Zero(t, device.Calibration())
+
+	store.OnChange(devices.Change{Kind: devices.DeviceAdded, SID: "1", Device: device})
+	require.Equal(t, devices.Calibration{TemperatureOffset: -0.5}, device.Calibration())
+	require.InDelta(t, 21, device.Temperature(), 0.0001)
+
+	other := &testThermometer{sid: "2"}
+	store.OnChange(devices.Change{Kind: devices.DeviceAdded, SID: "2", Device: other})
+	require.Zero(t, other.Calibration())
+}
@@ -0,0 +1,358 @@
+package config
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	DefaultPath = "/etc/infocenter/config.yaml"
+
+	defaultWebListen = ":80"
+	defaultWebRoot   = "./http"
+
+	defaultStorageDir = "/var/lib/infocenter"
+
+	minRawRetention = time.Hour
+
+	minAltitude = -500
+	maxAltitude = 9000
+)
+
+type Config struct {
+	Gateways []Gateway `yaml:"gateways"`
+	Sensors  []Sensor  `yaml:"sensors"`
+	Weather  Weather   `yaml:"weather"`
+	Web      Web       `yaml:"web"`
+	Storage  Storage   `yaml:"storage"`
+}
+
+type Gateway struct {
+	Name    string `yaml:"name"`
+	Address string `yaml:"address"` // resolved by SID when empty
+	Port    int    `yaml:"port"`
+	SID     string `yaml:"sid"`
+	Token   string `yaml:"token"`
+
+	EventsAddress string `yaml:"events_address"`
+
+	RequestTimeout time.Duration `yaml:"request_timeout"`
+	Retry          Retry         `yaml:"retry"`
+	RescanInterval time.Duration `yaml:"rescan_interval"`
+}
+
+// Retry describes how failed gateway requests are repeated, zero values and missing jitter select defaults.
+type Retry struct {
+	Attempts   int           `yaml:"attempts"`
+	Backoff    time.Duration `yaml:"backoff"`
+	MaxBackoff time.Duration `yaml:"max_backoff"`
+	Jitter     *float64      `yaml:"jitter"` // zero disables the jitter
+}
+
+// Sensor describes how a device is shown on the dashboard, devices not listed are shown after the listed ones.
+type Sensor struct {
+	SID    string `yaml:"sid"`
+	Name   string `yaml:"name"`
+	Room   string `yaml:"room"`
+	Icon   string `yaml:"icon"`  // Material Symbols icon name
+	Order  *int   `yaml:"order"` // listed sensors without order are shown after the ordered ones
+	Hidden bool   `yaml:"hidden"`
+}
+
+type Weather struct {
+	APIKey    string  `yaml:"api_key"`
+	Latitude  float64 `yaml:"latitude"`
+	Longitude float64 `yaml:"longitude"`
+	Altitude  float64 `yaml:"altitude"`  // in meters, barometers pressure is reduced to the sea level by it
+	Barometer string  `yaml:"barometer"` // SID of the local forecast barometer, the first found one when empty
+}
+
+type Web struct {
+	Listen string `yaml:"listen"`
+	Root   string `yaml:"root"`
+}
+
+// Storage describes where the state changed at runtime and the readings history are kept.
+type Storage struct {
+	Dir     string  `yaml:"dir"`
+	History History `yaml:"history"`
+}
+
+// History describes how long the readings are kept, zero values select defaults. Hourly averages are kept forever.
+type History struct {
+	RawRetention      time.Duration `yaml:"raw_retention"`
+	AveragesRetention time.Duration `yaml:"averages_retention"` // of 5-minute averages
+}
+
+// KeyError points at the configuration key holding an invalid value.
+type KeyError struct {
+	Key string
+	Err error
+}
+
+func (e *KeyError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Key, e.Err)
+}
+
+func (e *KeyError) Unwrap() error {
+	return e.Err
+}
+
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	cfg, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config '%v': %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+func Parse(data []byte) (*Config, error) {
+	cfg := &Config{
+		Web: Web{
+			Listen: defaultWebListen,
+			Root:   defaultWebRoot,
+		},
+		Storage: Storage{
+			Dir: defaultStorageDir,
+		},
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	err := decoder.Decode(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse: %w", err)
+	}
+
+	err = cfg.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+func (c *Config) validate() error {
+	return errors.Join(
+		c.validateGateways("gateways"),
+		c.validateSensors("sensors"),
+		c.Weather.validate("weather"),
+		c.Web.validate("web"),
+		c.Storage.validate("storage"),
+	)
+}
+
+func (c *Config) validateGateways(prefix string) error {
+	if len(c.Gateways) == 0 {
+		return &KeyError{Key: prefix, Err: errors.New("at least one gateway is required")}
+	}
+
+	var errs []error
+	names := make(map[string]int, len(c.Gateways))
+	for idx := range c.Gateways {
+		gateway := &c.Gateways[idx]
+		gatewayPrefix := fmt.Sprintf("%s[%d]", prefix, idx)
+
+		if otherIdx, fnd := names[gateway.Name]; fnd && gateway.Name != "" {
+			errs = append(errs, keyError(gatewayPrefix, "name",
+				fmt.Errorf("'%v' is already used by %s[%d]", gateway.Name, prefix, otherIdx)))
+		} else {
+			names[gateway.Name] = idx
+		}
+
+		errs = append(errs, gateway.validate(gatewayPrefix))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c *Config) validateSensors(prefix string) error {
+	var errs []error
+	sids := make(map[string]int, len(c.Sensors))
+	for idx := range c.Sensors {
+		sensor := &c.Sensors[idx]
+		sensorPrefix := fmt.Sprintf("%s[%d]", prefix, idx)
+
+		if otherIdx, fnd := sids[sensor.SID]; fnd && sensor.SID != "" {
+			errs = append(errs, keyError(sensorPrefix, "sid",
+				fmt.Errorf("'%v' is already described by %s[%d]", sensor.SID, prefix, otherIdx)))
+		} else {
+			sids[sensor.SID] = idx
+		}
+
+		errs = append(errs, sensor.validate(sensorPrefix))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (g *Gateway) validate(prefix string) error {
+	var errs []error
+
+	if g.Name == "" {
+		errs = append(errs, keyError(prefix, "name", errors.New("is required")))
+	}
+
+	if g.Address == "" && g.SID == "" {
+		errs = append(errs, keyError(prefix, "address", errors.New("is required when sid is not set")))
+	} else if g.Address != "" && net.ParseIP(g.Address) == nil {
+		errs = append(errs, keyError(prefix, "address", fmt.Errorf("'%v' is not an IP-address", g.Address)))
+	}
+
+	if g.Port < 0 || g.Port > 65535 {
+		errs = append(errs, keyError(prefix, "port", fmt.Errorf("%v is out of range [0, 65535]", g.Port)))
+	}
+
+	if g.EventsAddress != "" {
+		if _, _, err := net.SplitHostPort(g.EventsAddress); err != nil {
+			errs = append(errs, keyError(prefix, "events_address", err))
+		}
+	}
+
+	if _, err := hex.DecodeString(g.SID); err != nil {
+		errs = append(errs, keyError(prefix, "sid", fmt.Errorf("'%v' is not a hexadecimal SID", g.SID)))
+	}
+
+	if g.RequestTimeout < 0 {
+		errs = append(errs, keyError(prefix, "request_timeout", errors.New("must not be negative")))
+	}
+
+	if g.RescanInterval < 0 {
+		errs = append(errs, keyError(prefix, "rescan_interval", errors.New("must not be negative")))
+	}
+
+	errs = append(errs, g.Retry.validate(prefix+".retry"))
+
+	// the token is used as is as the AES key of the gateway requests
+	if _, err := aes.NewCipher([]byte(g.Token)); err != nil {
+		errs = append(errs, keyError(prefix, "token", errors.New("must be 16, 24 or 32 characters long")))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (s *Sensor) validate(prefix string) error {
+	if s.SID == "" {
+		return keyError(prefix, "sid", errors.New("is required"))
+	}
+
+	if _, err := hex.DecodeString(s.SID); err != nil {
+		return keyError(prefix, "sid", fmt.Errorf("'%v' is not a hexadecimal SID", s.SID))
+	}
+
+	return nil
+}
+
+func (r *Retry) validate(prefix string) error {
+	var errs []error
+
+	if r.Attempts < 0 {
+		errs = append(errs, keyError(prefix, "attempts", errors.New("must not be negative")))
+	}
+
+	if r.Backoff < 0 {
+		errs = append(errs, keyError(prefix, "backoff", errors.New("must not be negative")))
+	}
+
+	if r.MaxBackoff < 0 {
+		errs = append(errs, keyError(prefix, "max_backoff", errors.New("must not be negative")))
+	}
+
+	if r.Jitter != nil && (*r.Jitter < 0 || *r.Jitter > 1) {
+		errs = append(errs, keyError(prefix, "jitter", fmt.Errorf("%v is out of range [0, 1]", *r.Jitter)))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (w *Weather) validate(prefix string) error {
+	var errs []error
+
+	if w.APIKey == "" {
+		errs = append(errs, keyError(prefix, "api_key", errors.New("is required")))
+	}
+
+	if w.Latitude < -90 || w.Latitude > 90 {
+		errs = append(errs, keyError(prefix, "latitude", fmt.Errorf("%v is out of range [-90, 90]", w.Latitude)))
+	}
+
+	if w.Longitude < -180 || w.Longitude > 180 {
+		errs = append(errs, keyError(prefix, "longitude", fmt.Errorf("%v is out of range [-180, 180]", w.Longitude)))
+	}
+
+	if w.Altitude < minAltitude || w.Altitude > maxAltitude {
+		errs = append(errs, keyError(prefix, "altitude",
+			fmt.Errorf("%v is out of range [%v, %v]", w.Altitude, minAltitude, maxAltitude)))
+	}
+
+	if _, err := hex.DecodeString(w.Barometer); err != nil {
+		errs = append(errs, keyError(prefix, "barometer", fmt.Errorf("'%v' is not a hexadecimal SID", w.Barometer)))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (w *Web) validate(prefix string) error {
+	var errs []error
+
+	if _, _, err := net.SplitHostPort(w.Listen); err != nil {
+		errs = append(errs, keyError(prefix, "listen", err))
+	}
+
+	if w.Root == "" {
+		errs = append(errs, keyError(prefix, "root", errors.New("is required")))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (s *Storage) validate(prefix string) error {
+	var errs []error
+
+	if s.Dir == "" {
+		errs = append(errs, keyError(prefix, "dir", errors.New("is required")))
+	}
+
+	errs = append(errs, s.History.validate(prefix+".history"))
+
+	return errors.Join(errs...)
+}
+
+func (h *History) validate(prefix string) error {
+	var errs []error
+
+	// the hourly averages not written yet are restored from the raw readings after a restart
+	switch {
+	case h.RawRetention < 0:
+		errs = append(errs, keyError(prefix, "raw_retention", errors.New("must not be negative")))
+	case h.RawRetention > 0 && h.RawRetention < minRawRetention:
+		errs = append(errs, keyError(prefix, "raw_retention", fmt.Errorf("must be at least %v", minRawRetention)))
+	}
+
+	if h.AveragesRetention < 0 {
+		errs = append(errs, keyError(prefix, "averages_retention", errors.New("must not be negative")))
+	}
+
+	return errors.Join(errs...)
+}
+
+func keyError(prefix string, key string, err error) error {
+	return &KeyError{
+		Key: prefix + "." + key,
+		Err: err,
+	}
+}
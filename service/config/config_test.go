@@ -0,0 +1,108 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const validConfig = `
+gateways:
+  - name: ground
+    address: 192.168.31.21
+    token: 0123456789abcdef
+    retry:
+      attempts: 5
+      backoff: 250ms
+      jitter: 0
+sensors:
+  - sid: 158d0001fd4989
+    name: Kitchen
+    room: kitchen
+    order: 1
+  - sid: 158d0001f57fee
+    hidden: true
+weather:
+  api_key: key
+  latitude: 59.89
+  longitude: 30.31
+`
+
+func TestParse(t *testing.T) {
+	cfg, err := Parse([]byte(validConfig))
+	require.NoError(t, err)
+	require.Len(t, cfg.Gateways, 1)
+	require.Equal(t, "192.168.31.21", cfg.Gateways[0].Address)
+	require.Equal(t, 5, cfg.Gateways[0].Retry.Attempts)
+	require.Equal(t, 250*time.Millisecond, cfg.Gateways[0].Retry.Backoff)
+	require.NotNil(t, cfg.Gateways[0].Retry.Jitter)
+	require.Zero(t, *cfg.Gateways[0].Retry.Jitter)
+	require.Len(t, cfg.Sensors, 2)
+	require.Equal(t, "Kitchen", cfg.Sensors[0].Name)
+	require.Equal(t, 1, *cfg.Sensors[0].Order)
+	require.Nil(t, cfg.Sensors[1].Order)
+	require.True(t, cfg.Sensors[1].Hidden)
+	require.Equal(t, defaultWebListen, cfg.Web.Listen)
+	require.Equal(t, defaultWebRoot, cfg.Web.Root)
+	require.Equal(t, defaultStorageDir, cfg.Storage.Dir)
+
+	cfg, err = Parse([]byte(`
+gateways:
+  - name: ground
+    address: 192.168.31.21
+    token: 540b4bf40bb290ef62004d27fc3438e6
+weather:
+  api_key: key
+`))
+	require.NoError(t, err)
+	require.Equal(t, "540b4bf40bb290ef62004d27fc3438e6", cfg.Gateways[0].Token)
+}
+
+func TestParseInvalidKey(t *testing.T) {
+	_, err := Parse([]byte(`
+gateways:
+  - name: ground
+    address: 192.168.31
+    token: 0123456789abcdef
+  - name: ground
+    address: 192.168.31.22
+    token: 0123456789abcdef
+  - name: attic
+    sid: 7811dcb2xx
+    token: 0123456789abcdef0
+    retry:
+      jitter: 1.5
+sensors:
+  - sid: 158d0001fd4989
+  - sid: 158d0001fd4989
+  - name: Nowhere
+weather:
+  api_key: key
+  latitude: 95
+  altitude: 10000
+  barometer: barometer
+storage:
+  history:
+    raw_retention: -1h
+`))
+	require.ErrorContains(t, err, "gateways[0].address: '192.168.31' is not an IP-address")
+	require.ErrorContains(t, err, "gateways[1].name: 'ground' is already used by gateways[0]")
+	require.ErrorContains(t, err, "gateways[2].sid: '7811dcb2xx' is not a hexadecimal SID")
+	require.ErrorContains(t, err, "gateways[2].token: must be 16, 24 or 32 characters long")
+	require.ErrorContains(t, err, "gateways[2].retry.jitter: 1.5 is out of range [0, 1]")
+	require.ErrorContains(t, err, "sensors[1].sid: '158d0001fd4989' is already described by sensors[0]")
+	require.ErrorContains(t, err, "sensors[2].sid: is required")
+	require.ErrorContains(t, err, "weather.latitude: 95 is out of range")
+	require.ErrorContains(t, err, "weather.altitude: 10000 is out of range")
+	require.ErrorContains(t, err, "weather.barometer: 'barometer' is not a hexadecimal SID")
+	require.ErrorContains(t, err, "storage.history.raw_retention: must not be negative")
+
+	_, err = Parse([]byte(validConfig + "storage:\n  history:\n    raw_retention: 30m\n"))
+	require.ErrorContains(t, err, "storage.history.raw_retention: must be at least 1h0m0s")
+}
+
+func TestParseUnknownKey(t *testing.T) {
+	_, err := Parse([]byte(validConfig + "unknown: 1\n"))
+	require.ErrorContains(t, err, "field unknown not found")
+}
@@ -0,0 +1,54 @@
+package devices
+
+// Calibration corrects the measurements of a device: the raw value is multiplied by the scale
+// and the offset is added. Zero scale means no scaling.
+type Calibration struct {
+	TemperatureOffset float32 `json:"temperature_offset"` // in °C
+	TemperatureScale  float32 `json:"temperature_scale"`
+	HumidityOffset    float32 `json:"humidity_offset"` // in percents
+	HumidityScale     float32 `json:"humidity_scale"`
+	PressureOffset    float32 `json:"pressure_offset"` // in Pascals
+	PressureScale     float32 `json:"pressure_scale"`
+}
+
+func (c Calibration) Temperature(raw float32) float32 {
+	return calibrate(raw, c.TemperatureScale, c.TemperatureOffset)
+}
+
+func (c Calibration) Humidity(raw float32) float32 {
+	humidity := calibrate(raw, c.HumidityScale, c.HumidityOffset)
+	switch {
+	case humidity < 0:
+		return 0
+	case humidity > 100:
+		return 100
+	default:
+		return humidity
+	}
+}
+
+func (c Calibration) Pressure(raw float32) float32 {
+	return calibrate(raw, c.PressureScale, c.PressureOffset)
+}
+
+func calibrate(raw float32, scale float32, offset float32) float32 {
+	if scale != 0 {
+		raw *= scale
+	}
+
+	return raw + offset
+}
+
+// Measurements are the values as reported by a device, nil when the device doesn't measure it.
+type Measurements struct {
+	Temperature *float32 `json:"temperature,omitempty"`
+	Humidity    *float32 `json:"humidity,omitempty"`
+	Pressure    *float32 `json:"pressure,omitempty"`
+}
+
+// Calibratable is a device applying a calibration to its measurements.
+type Calibratable interface {
+	Calibration() Calibration
+	SetCalibration(calibration Calibration)
+	RawMeasurements() Measurements
+}
@@ -0,0 +1,159 @@
+package devices
+
+import "strings"
+
+// Capability is a device interface the registry detects, so consumers check a bit instead of asserting types.
+type Capability uint32
+
+const (
+	CapabilityGatewayChild Capability = 1 << iota
+	CapabilityBattery
+	CapabilityTemperature
+	CapabilityHumidity
+	CapabilityPressure
+	CapabilityContact
+	CapabilityMotion
+	CapabilityIlluminance
+	CapabilityAlarm
+	CapabilityDensity
+	CapabilitySwitch
+	CapabilityLight
+	CapabilityEvents
+	CapabilityRaw
+	CapabilityLiveness
+	CapabilityCalibration
+	CapabilityComfort
+
+	lastCapability = CapabilityComfort
+)
+
+var capabilityNames = map[Capability]string{
+	CapabilityGatewayChild: "gateway_child",
+	CapabilityBattery:      "battery",
+	CapabilityTemperature:  "temperature",
+	CapabilityHumidity:     "humidity",
+	CapabilityPressure:     "pressure",
+	CapabilityContact:      "contact",
+	CapabilityMotion:       "motion",
+	CapabilityIlluminance:  "illuminance",
+	CapabilityAlarm:        "alarm",
+	CapabilityDensity:      "density",
+	CapabilitySwitch:       "switch",
+	CapabilityLight:        "light",
+	CapabilityEvents:       "events",
+	CapabilityRaw:          "raw",
+	CapabilityLiveness:     "liveness",
+	CapabilityCalibration:  "calibration",
+	CapabilityComfort:      "comfort",
+}
+
+func (c Capability) String() string {
+	if name, fnd := capabilityNames[c]; fnd {
+		return name
+	}
+
+	return "unknown"
+}
+
+// Capabilities is a set of capabilities.
+type Capabilities Capability
+
+func (c Capabilities) Has(capability Capability) bool {
+	return Capability(c)&capability != 0
+}
+
+// List returns the capabilities of the set in the order of their declaration.
+func (c Capabilities) List() []Capability {
+	list := make([]Capability, 0)
+	for capability := Capability(1); capability <= lastCapability; capability <<= 1 {
+		if c.Has(capability) {
+			list = append(list, capability)
+		}
+	}
+
+	return list
+}
+
+func (c Capabilities) String() string {
+	names := make([]string, 0)
+	for _, capability := range c.List() {
+		names = append(names, capability.String())
+	}
+
+	return strings.Join(names, ",")
+}
+
+// CapabilitiesOf detects which device interfaces the device implements.
+func CapabilitiesOf(device Device) Capabilities {
+	var c Capability
+
+	if _, ok := device.(GatewayChild); ok {
+		c |= CapabilityGatewayChild
+	}
+
+	if _, ok := device.(BatteryPowered); ok {
+		c |= CapabilityBattery
+	}
+
+	if _, ok := device.(Thermometer); ok {
+		c |= CapabilityTemperature
+	}
+
+	if _, ok := device.(Hygrometer); ok {
+		c |= CapabilityHumidity
+	}
+
+	if _, ok := device.(Barometer); ok {
+		c |= CapabilityPressure
+	}
+
+	if _, ok := device.(ContactSensor); ok {
+		c |= CapabilityContact
+	}
+
+	if _, ok := device.(MotionSensor); ok {
+		c |= CapabilityMotion
+	}
+
+	if _, ok := device.(Illuminometer); ok {
+		c |= CapabilityIlluminance
+	}
+
+	if _, ok := device.(AlarmSensor); ok {
+		c |= CapabilityAlarm
+	}
+
+	if _, ok := device.(DensitySensor); ok {
+		c |= CapabilityDensity
+	}
+
+	if _, ok := device.(Switchable); ok {
+		c |= CapabilitySwitch
+	}
+
+	if _, ok := device.(RGBLight); ok {
+		c |= CapabilityLight
+	}
+
+	if _, ok := device.(EventSource); ok {
+		c |= CapabilityEvents
+	}
+
+	if _, ok := device.(RawDevice); ok {
+		c |= CapabilityRaw
+	}
+
+	if _, ok := device.(Monitored); ok {
+		c |= CapabilityLiveness
+	}
+
+	if _, ok := device.(Calibratable); ok {
+		c |= CapabilityCalibration
+	}
+
+	if _, ok := ComfortMeterOf(device); ok {
+		c |= CapabilityComfort
+	}
+
+	return Capabilities(c)
+}
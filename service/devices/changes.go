@@ -0,0 +1,39 @@
+package devices
+
+type ChangeKind int
+
+const (
+	DeviceAdded ChangeKind = iota
+	DeviceRemoved
+	DeviceLivenessChanged
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case DeviceAdded:
+		return "added"
+	case DeviceRemoved:
+		return "removed"
+	case DeviceLivenessChanged:
+		return "liveness changed"
+	default:
+		return "unknown"
+	}
+}
+
+// Change describes a device paired to or removed from a gateway at runtime, or its liveness change.
+type Change struct {
+	Kind    ChangeKind
+	SID     string
+	Model   string
+	Gateway string
+	Device  Device
+
+	Liveness Liveness // new liveness for DeviceLivenessChanged
+}
+
+type ChangeConsumeFunc func(change Change)
+
+type ChangeNotifier interface {
+	RegisterChangeConsumer(consumeFunc ChangeConsumeFunc)
+}
@@ -0,0 +1,124 @@
+package devices
+
+import "math"
+
+const (
+	// Magnus formula coefficients over water, by Alduchov and Eskridge
+	magnusB = 17.625
+	magnusC = 243.04 // in °C
+
+	waterVaporGasConstant = 461.5 // in J/(kg·K)
+	zeroCelsius           = 273.15
+
+	// dew point of the absolutely dry air is undefined, it is computed for this humidity instead
+	minComfortHumidity = 0.01
+)
+
+var _ ComfortMeter = climateComfortMeter{}
+
+// ComfortMeter derives the comfort metrics from the temperature and humidity.
+type ComfortMeter interface {
+	DewPoint() float32         // in °C
+	AbsoluteHumidity() float32 // in g/m³
+	HeatIndex() float32        // apparent temperature by NOAA, in °C
+	Humidex() float32          // apparent temperature by Environment Canada, in °C
+}
+
+// ComfortMeterOf returns the comfort meter of a device measuring both the temperature and humidity.
+func ComfortMeterOf(device Device) (ComfortMeter, bool) {
+	if meter, ok := device.(ComfortMeter); ok {
+		return meter, true
+	}
+
+	thermometer, ok := device.(Thermometer)
+	if !ok {
+		return nil, false
+	}
+
+	hygrometer, ok := device.(Hygrometer)
+	if !ok {
+		return nil, false
+	}
+
+	return climateComfortMeter{thermometer: thermometer, hygrometer: hygrometer}, true
+}
+
+type climateComfortMeter struct {
+	thermometer Thermometer
+	hygrometer  Hygrometer
+}
+
+func (m climateComfortMeter) DewPoint() float32 {
+	return DewPoint(m.thermometer.Temperature(), m.hygrometer.Humidity())
+}
+
+func (m climateComfortMeter) AbsoluteHumidity() float32 {
+	return AbsoluteHumidity(m.thermometer.Temperature(), m.hygrometer.Humidity())
+}
+
+func (m climateComfortMeter) HeatIndex() float32 {
+	return HeatIndex(m.thermometer.Temperature(), m.hygrometer.Humidity())
+}
+
+func (m climateComfortMeter) Humidex() float32 {
+	return Humidex(m.thermometer.Temperature(), m.hygrometer.Humidity())
+}
+
+// DewPoint returns the temperature in °C the air of the temperature in °C and the relative humidity
+// in percents needs to be cooled to for the water vapor to condense.
+func DewPoint(temperature float32, humidity float32) float32 {
+	gamma := math.Log(float64(clampHumidity(humidity))/100) + magnusB*float64(temperature)/(magnusC+float64(temperature))
+	return float32(magnusC * gamma / (magnusB - gamma))
+}
+
+// AbsoluteHumidity returns the mass of the water vapor in the air in g/m³.
+func AbsoluteHumidity(temperature float32, humidity float32) float32 {
+	vaporPressure := saturationVaporPressure(float64(temperature)) * float64(clampHumidity(humidity)) / 100
+	return float32(vaporPressure / (waterVaporGasConstant * (float64(temperature) + zeroCelsius)) * 1000)
+}
+
+// HeatIndex returns how hot the air feels by the NOAA heat index algorithm in °C.
+// It is meant for the warm air, below 27°C it is close to the temperature.
+func HeatIndex(temperature float32, humidity float32) float32 {
+	t := float64(temperature)*9/5 + 32
+	rh := float64(clampHumidity(humidity))
+
+	index := 0.5 * (t + 61 + (t-68)*1.2 + rh*0.094)
+	if (index+t)/2 >= 80 {
+		index = -42.379 + 2.04901523*t + 10.14333127*rh - 0.22475541*t*rh - 0.00683783*t*t -
+			0.05481717*rh*rh + 0.00122874*t*t*rh + 0.00085282*t*rh*rh - 0.00000199*t*t*rh*rh
+
+		switch {
+		case rh < 13 && t >= 80 && t <= 112:
+			index -= (13 - rh) / 4 * math.Sqrt((17-math.Abs(t-95))/17)
+		case rh > 85 && t >= 80 && t <= 87:
+			index += (rh - 85) / 10 * (87 - t) / 5
+		}
+	}
+
+	return float32((index - 32) * 5 / 9)
+}
+
+// Humidex returns how hot the air feels by the Environment Canada humidex in °C.
+func Humidex(temperature float32, humidity float32) float32 {
+	dewPoint := float64(DewPoint(temperature, humidity))
+	vaporPressure := 6.11 * math.Exp(5417.7530*(1/273.16-1/(dewPoint+zeroCelsius)))
+
+	return float32(float64(temperature) + 0.5555*(vaporPressure-10))
+}
+
+// saturationVaporPressure returns the water vapor pressure over water in Pascals by the Magnus formula.
+func saturationVaporPressure(temperature float64) float64 {
+	return 611.2 * math.Exp(magnusB*temperature/(magnusC+temperature))
+}
+
+func clampHumidity(humidity float32) float32 {
+	switch {
+	case humidity < minComfortHumidity:
+		return minComfortHumidity
+	case humidity > 100:
+		return 100
+	default:
+		return humidity
+	}
+}
@@ -0,0 +1,41 @@
+package devices
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestComfortMetrics(t *testing.T) {
+	require.InDelta(t, 9.3, DewPoint(20, 50), 0.1)
+	require.InDelta(t, 23.9, DewPoint(30, 70), 0.1)
+	require.InDelta(t, 20, DewPoint(20, 100), 0.01)
+	require.Less(t, DewPoint(20, 0), float32(-70))
+
+	require.InDelta(t, 8.6, AbsoluteHumidity(20, 50), 0.1)
+	require.InDelta(t, 30.3, AbsoluteHumidity(30, 100), 0.2)
+
+	// NOAA heat index table: 86°F at 70% feels like 95°F, 96°F at 50% feels like 108°F
+	require.InDelta(t, 35, HeatIndex(30, 70), 0.3)
+	require.InDelta(t, 42.2, HeatIndex(35.6, 50), 0.3)
+	require.InDelta(t, 20, HeatIndex(20, 50), 1)
+
+	// Environment Canada humidex table: 30°C with 24°C dew point feels like 41°C
+	require.InDelta(t, 41, Humidex(30, 70.4), 0.5)
+}
+
+type testClimateSensor struct {
+	testDevice
+}
+
+func (d *testClimateSensor) Temperature() float32 { return 30 }
+func (d *testClimateSensor) Humidity() float32    { return 70 }
+
+func TestComfortMeterOf(t *testing.T) {
+	meter, ok := ComfortMeterOf(&testClimateSensor{testDevice{sid: "1"}})
+	require.True(t, ok)
+	require.InDelta(t, 23.9, meter.DewPoint(), 0.1)
+
+	_, ok = ComfortMeterOf(&testDevice{sid: "2"})
+	require.False(t, ok)
+}
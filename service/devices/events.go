@@ -0,0 +1,46 @@
+package devices
+
+import "time"
+
+type EventKind string
+
+const (
+	EventClick            EventKind = "click"
+	EventDoubleClick      EventKind = "double_click"
+	EventLongClickPress   EventKind = "long_click_press"
+	EventLongClickRelease EventKind = "long_click_release"
+	EventBothClick        EventKind = "both_click"
+
+	EventFlip90   EventKind = "flip90"
+	EventFlip180  EventKind = "flip180"
+	EventMove     EventKind = "move"
+	EventTapTwice EventKind = "tap_twice"
+	EventShakeAir EventKind = "shake_air"
+	EventSwing    EventKind = "swing"
+	EventAlert    EventKind = "alert"
+	EventFreeFall EventKind = "free_fall"
+	EventRotate   EventKind = "rotate"
+)
+
+// Event is a transient action reported by a device such as a button click or a cube flip,
+// unlike sensor readings it has no state to be polled.
+type Event struct {
+	Kind    EventKind
+	SID     string
+	Model   string
+	Gateway string
+	At      time.Time
+	Angle   int // rotation angle in degrees for EventRotate, negative is counterclockwise
+}
+
+type EventConsumeFunc func(event Event)
+
+type EventNotifier interface {
+	RegisterEventConsumer(consumeFunc EventConsumeFunc)
+}
+
+// EventSource is a device producing events.
+type EventSource interface {
+	EventNotifier
+	LastEvent() (Event, bool) // false until the first event
+}
@@ -1,12 +1,20 @@
 package devices
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type Device interface {
 	SID() string
+	Model() string
 	LastUpdateAt() time.Time
 }
 
+type GatewayChild interface {
+	Gateway() string // name of the gateway the device is paired with
+}
+
 type BatteryPowered interface {
 	BatteryVoltage() float32
 }
@@ -23,6 +31,50 @@ type Barometer interface {
 	Pressure() float32 // in Pascals
 }
 
-type Sensors interface {
-	Sensors() []interface{}
+type ContactSensor interface {
+	Open() bool
+	LastChangeAt() time.Time // zero until the contact is opened or closed
+	OpenFor() time.Duration  // how long the contact is left open by "no_close" reports, zero when closed
+}
+
+type MotionSensor interface {
+	Occupied() bool
+	LastMotionAt() time.Time // zero until motion is detected
+	IdleFor() time.Duration  // how long no motion is detected by "no_motion" reports, zero while occupied
+}
+
+type Illuminometer interface {
+	Illuminance() float32 // in lux
+}
+
+type AlarmSensor interface {
+	Alarm() bool
+	AlarmChangedAt() time.Time // zero until the alarm is raised or released
+	LastSelfTestAt() time.Time // zero until a self-test is reported
+}
+
+type DensitySensor interface {
+	Density() float32 // smoke or gas density as reported by the detector
+}
+
+type Switchable interface {
+	Channels() int
+	On(channel int) bool
+	Switch(channel int, on bool) error
+}
+
+type RGBLight interface {
+	RGB() uint32       // color as 0xRRGGBB
+	Brightness() uint8 // in percents, zero when the light is off
+	SetLight(rgb uint32, brightness uint8) error
+}
+
+// RawDevice is a device of a model without dedicated support, it only exposes the reported data.
+type RawDevice interface {
+	RawData() json.RawMessage // the last value of every reported field
+}
+
+// Source provides devices, e.g. a gateway with its child devices.
+type Source interface {
+	Devices() []Device
 }
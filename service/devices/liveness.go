@@ -0,0 +1,43 @@
+package devices
+
+import "time"
+
+type Liveness int
+
+const (
+	Online Liveness = iota
+	Stale           // a heartbeat is missed, the device may be just late
+	Offline
+)
+
+func (l Liveness) String() string {
+	switch l {
+	case Online:
+		return "online"
+	case Stale:
+		return "stale"
+	case Offline:
+		return "offline"
+	default:
+		return "unknown"
+	}
+}
+
+// LivenessOf tells the liveness of a device expected to send data at least once in the heartbeat interval:
+// it is stale after a heartbeat is missed and offline after three of them are missed.
+func LivenessOf(sinceLastUpdate time.Duration, heartBeatInterval time.Duration) Liveness {
+	switch {
+	case sinceLastUpdate > 3*heartBeatInterval:
+		return Offline
+	case sinceLastUpdate > heartBeatInterval+heartBeatInterval/2:
+		return Stale
+	default:
+		return Online
+	}
+}
+
+// Monitored is a device with liveness tracked by its heartbeats.
+type Monitored interface {
+	Liveness() Liveness
+	HeartBeatInterval() time.Duration
+}
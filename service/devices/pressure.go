@@ -0,0 +1,19 @@
+package devices
+
+import "math"
+
+// International Standard Atmosphere barometric formula constants
+const (
+	isaLapseFactor = 2.25577e-5 // temperature lapse rate divided by the sea level temperature, per meter
+	isaExponent    = 5.25588
+)
+
+// SeaLevelPressure reduces the pressure measured at the altitude in meters to the sea level by the standard atmosphere.
+func SeaLevelPressure(stationPressure float32, altitude float64) float32 {
+	return float32(float64(stationPressure) / math.Pow(1-isaLapseFactor*altitude, isaExponent))
+}
+
+// StationPressure is the reverse of SeaLevelPressure.
+func StationPressure(seaLevelPressure float32, altitude float64) float32 {
+	return float32(float64(seaLevelPressure) * math.Pow(1-isaLapseFactor*altitude, isaExponent))
+}
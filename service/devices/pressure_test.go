@@ -0,0 +1,14 @@
+package devices
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeaLevelPressure(t *testing.T) {
+	// standard atmosphere pressure at 100 m is 1001.29 hPa
+	require.InDelta(t, 101325, SeaLevelPressure(100129, 100), 5)
+	require.InDelta(t, 100129, StationPressure(101325, 100), 5)
+	require.Equal(t, float32(101325), SeaLevelPressure(101325, 0))
+}
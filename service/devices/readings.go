@@ -0,0 +1,39 @@
+package devices
+
+import "time"
+
+type Metric string
+
+const (
+	MetricTemperature Metric = "temperature" // in °C
+	MetricHumidity    Metric = "humidity"    // in percents
+	MetricPressure    Metric = "pressure"    // in Pascals
+	MetricIlluminance Metric = "illuminance" // in lux
+)
+
+var metrics = map[Metric]struct{}{
+	MetricTemperature: {},
+	MetricHumidity:    {},
+	MetricPressure:    {},
+	MetricIlluminance: {},
+}
+
+// Known is false for the metrics no device reports.
+func (m Metric) Known() bool {
+	_, fnd := metrics[m]
+	return fnd
+}
+
+// Reading is a measured value as reported by a device, after the calibration.
+type Reading struct {
+	SID    string
+	Metric Metric
+	Value  float32
+	At     time.Time
+}
+
+type ReadingConsumeFunc func(reading Reading)
+
+type ReadingNotifier interface {
+	RegisterReadingConsumer(consumeFunc ReadingConsumeFunc)
+}
@@ -0,0 +1,189 @@
+package devices
+
+import (
+	"log"
+	"sync"
+)
+
+var (
+	_ ChangeNotifier  = &Registry{}
+	_ EventNotifier   = &Registry{}
+	_ ReadingNotifier = &Registry{}
+)
+
+// Entry is a registered device with its capabilities detected once on registration.
+type Entry struct {
+	Device       Device
+	Capabilities Capabilities
+}
+
+// Registry keeps the devices of all the sources by SID in the order they were added.
+type Registry struct {
+	entries map[string]Entry
+	order   []string
+	mutex   sync.RWMutex
+
+	sources      []Source
+	sourcesMutex sync.Mutex
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		entries: make(map[string]Entry),
+	}
+}
+
+// Attach adds the devices of the source and follows its changes, sources should be attached before they start.
+func (r *Registry) Attach(source Source) {
+	func() {
+		r.sourcesMutex.Lock()
+		defer r.sourcesMutex.Unlock()
+
+		r.sources = append(r.sources, source)
+	}()
+
+	if notifier, ok := source.(ChangeNotifier); ok {
+		notifier.RegisterChangeConsumer(r.onChange)
+	}
+
+	for _, device := range source.Devices() {
+		r.Add(device)
+	}
+}
+
+// Add registers the device, replacing the one with the same SID in place, and returns its entry.
+func (r *Registry) Add(device Device) Entry {
+	entry := Entry{
+		Device:       device,
+		Capabilities: CapabilitiesOf(device),
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, fnd := r.entries[device.SID()]; !fnd {
+		r.order = append(r.order, device.SID())
+	}
+
+	r.entries[device.SID()] = entry
+
+	return entry
+}
+
+// Remove unregisters the device, false when it is not registered.
+func (r *Registry) Remove(sid string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, fnd := r.entries[sid]; !fnd {
+		return false
+	}
+
+	delete(r.entries, sid)
+	for idx, orderSID := range r.order {
+		if orderSID == sid {
+			r.order = append(r.order[:idx:idx], r.order[idx+1:]...)
+			break
+		}
+	}
+
+	return true
+}
+
+func (r *Registry) Get(sid string) (Entry, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	entry, fnd := r.entries[sid]
+	return entry, fnd
+}
+
+// Entries returns all the registered devices.
+func (r *Registry) Entries() []Entry {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	entries := make([]Entry, 0, len(r.order))
+	for _, sid := range r.order {
+		entries = append(entries, r.entries[sid])
+	}
+
+	return entries
+}
+
+// WithCapability returns the registered devices having the capability.
+func (r *Registry) WithCapability(capability Capability) []Entry {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	entries := make([]Entry, 0)
+	for _, sid := range r.order {
+		if entry := r.entries[sid]; entry.Capabilities.Has(capability) {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
+
+// Lookup returns the registered device as T, false when it is not registered or doesn't implement T.
+func Lookup[T any](r *Registry, sid string) (T, bool) {
+	var zero T
+
+	entry, fnd := r.Get(sid)
+	if !fnd {
+		return zero, false
+	}
+
+	device, ok := entry.Device.(T)
+	if !ok {
+		return zero, false
+	}
+
+	return device, true
+}
+
+// RegisterChangeConsumer registers the consumer within every attached source able to notify about changes.
+func (r *Registry) RegisterChangeConsumer(consumeFunc ChangeConsumeFunc) {
+	for _, source := range r.attachedSources() {
+		if notifier, ok := source.(ChangeNotifier); ok {
+			notifier.RegisterChangeConsumer(consumeFunc)
+		}
+	}
+}
+
+// RegisterEventConsumer registers the consumer within every attached source able to notify about device events.
+func (r *Registry) RegisterEventConsumer(consumeFunc EventConsumeFunc) {
+	for _, source := range r.attachedSources() {
+		if notifier, ok := source.(EventNotifier); ok {
+			notifier.RegisterEventConsumer(consumeFunc)
+		}
+	}
+}
+
+// RegisterReadingConsumer registers the consumer within every attached source able to notify about device readings.
+func (r *Registry) RegisterReadingConsumer(consumeFunc ReadingConsumeFunc) {
+	for _, source := range r.attachedSources() {
+		if notifier, ok := source.(ReadingNotifier); ok {
+			notifier.RegisterReadingConsumer(consumeFunc)
+		}
+	}
+}
+
+func (r *Registry) attachedSources() []Source {
+	r.sourcesMutex.Lock()
+	defer r.sourcesMutex.Unlock()
+
+	return append([]Source(nil), r.sources...)
+}
+
+func (r *Registry) onChange(change Change) {
+	switch change.Kind {
+	case DeviceAdded:
+		entry := r.Add(change.Device)
+		log.Printf("Device '%v' of model '%v' registered with capabilities '%v'",
+			change.SID, change.Model, entry.Capabilities)
+	case DeviceRemoved:
+		r.Remove(change.SID)
+	}
+}
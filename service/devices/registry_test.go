@@ -0,0 +1,68 @@
+package devices
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testDevice struct {
+	sid string
+}
+
+func (d *testDevice) SID() string             { return d.sid }
+func (d *testDevice) Model() string           { return "test" }
+func (d *testDevice) LastUpdateAt() time.Time { return time.Time{} }
+
+type testThermometer struct {
+	testDevice
+}
+
+func (d *testThermometer) Temperature() float32    { return 21.5 }
+func (d *testThermometer) BatteryVoltage() float32 { return 3 }
+
+type testSource struct {
+	devices  []Device
+	consumer ChangeConsumeFunc
+}
+
+func (s *testSource) Devices() []Device { return s.devices }
+
+func (s *testSource) RegisterChangeConsumer(consumeFunc ChangeConsumeFunc) {
+	s.consumer = consumeFunc
+}
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+	plain := &testDevice{sid: "1"}
+	thermometer := &testThermometer{testDevice{sid: "2"}}
+
+	source := &testSource{devices: []Device{plain}}
+	registry.Attach(source)
+	source.consumer(Change{Kind: DeviceAdded, SID: "2", Device: thermometer})
+
+	entries := registry.Entries()
+	require.Len(t, entries, 2)
+	require.Equal(t, "1", entries[0].Device.SID())
+	require.Equal(t, "2", entries[1].Device.SID())
+	require.Equal(t, "battery,temperature", entries[1].Capabilities.String())
+	require.Zero(t, entries[0].Capabilities)
+
+	found, ok := Lookup[Thermometer](registry, "2")
+	require.True(t, ok)
+	require.Equal(t, float32(21.5), found.Temperature())
+
+	_, ok = Lookup[Thermometer](registry, "1")
+	require.False(t, ok)
+
+	withTemperature := registry.WithCapability(CapabilityTemperature)
+	require.Len(t, withTemperature, 1)
+	require.Equal(t, "2", withTemperature[0].Device.SID())
+
+	source.consumer(Change{Kind: DeviceRemoved, SID: "1", Device: plain})
+	_, ok = registry.Get("1")
+	require.False(t, ok)
+	require.False(t, registry.Remove("1"))
+	require.Len(t, registry.Entries(), 1)
+}
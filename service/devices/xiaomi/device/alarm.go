@@ -0,0 +1,46 @@
+package device
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// alarm holds the state of devices raising alarms: leak, smoke and gas detectors.
+type alarm struct {
+	active         atomic.Bool
+	changedAt      atomic.Pointer[time.Time]
+	lastSelfTestAt atomic.Pointer[time.Time]
+}
+
+func (a *alarm) Alarm() bool {
+	return a.active.Load()
+}
+
+func (a *alarm) AlarmChangedAt() time.Time {
+	ptr := a.changedAt.Load()
+	if ptr == nil {
+		return time.Time{}
+	}
+
+	return *ptr
+}
+
+func (a *alarm) LastSelfTestAt() time.Time {
+	ptr := a.lastSelfTestAt.Load()
+	if ptr == nil {
+		return time.Time{}
+	}
+
+	return *ptr
+}
+
+// storeAlarm updates the alarm state, only reports mark changes as they happen at the time of receiving.
+func (a *alarm) storeAlarm(active bool, isReport bool, at time.Time) {
+	if a.active.Swap(active) != active && isReport {
+		a.changedAt.Store(&at)
+	}
+}
+
+func (a *alarm) storeSelfTest(at time.Time) {
+	a.lastSelfTestAt.Store(&at)
+}
@@ -0,0 +1,102 @@
+package device
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/cherserver/infocenter/service/devices"
+	"github.com/cherserver/infocenter/service/devices/xiaomi/transport"
+)
+
+func init() {
+	registerModels(NewButton, "switch", "sensor_switch.aq2")
+}
+
+var buttonEvents = map[string]devices.EventKind{
+	"click":              devices.EventClick,
+	"double_click":       devices.EventDoubleClick,
+	"long_click_press":   devices.EventLongClickPress,
+	"long_click_release": devices.EventLongClickRelease,
+	"both_click":         devices.EventBothClick,
+}
+
+var (
+	_ devices.Device         = &Button{}
+	_ devices.GatewayChild   = &Button{}
+	_ devices.BatteryPowered = &Button{}
+	_ devices.EventSource    = &Button{}
+)
+
+type buttonData struct {
+	Voltage *int    `json:"voltage"`
+	Status  *string `json:"status"`
+}
+
+// Button is a "switch" or "sensor_switch.aq2" wireless button, clicks are reported as events.
+type Button struct {
+	common
+	battery
+	emitter
+}
+
+func NewButton(gateway *transport.Transport, sid string, model string, initData string) (*Button, error) {
+	sensor := &Button{}
+	sensor.init(sid, model, gateway.Name())
+
+	err := sensor.parseData(initData, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %v with sid '%v', can't parse init data: %w", model, sid, err)
+	}
+
+	gateway.RegisterHeartBeatConsumer(sensor.SID(), sensor.OnHeartBeat)
+	gateway.RegisterReportConsumer(sensor.SID(), sensor.OnReport)
+
+	return sensor, nil
+}
+
+// OnHeartBeat doesn't emit events, the heartbeat repeats the status of the last click.
+func (s *Button) OnHeartBeat(data string) {
+	_ = s.parseData(data, false)
+}
+
+func (s *Button) OnReport(data string) {
+	_ = s.parseData(data, true)
+}
+
+func (s *Button) parseData(data string, report bool) error {
+	var parsedData buttonData
+	err := json.Unmarshal([]byte(data), &parsedData)
+	if err != nil {
+		return fmt.Errorf("failed to parse data: %w", err)
+	}
+
+	now := time.Now()
+	s.storeVoltage(parsedData.Voltage)
+
+	var kind devices.EventKind
+	if parsedData.Status != nil {
+		var fnd bool
+		kind, fnd = buttonEvents[*parsedData.Status]
+		if !fnd {
+			return fmt.Errorf("failed to parse status from '%v'", *parsedData.Status)
+		}
+	}
+
+	s.touch(now)
+
+	if report && kind != "" {
+		log.Printf("New '%s' device event '%v'", s.sid, kind)
+
+		s.emit(devices.Event{
+			Kind:    kind,
+			SID:     s.sid,
+			Model:   s.model,
+			Gateway: s.gateway,
+			At:      now,
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,137 @@
+package device
+
+import (
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/cherserver/infocenter/service/devices"
+)
+
+// defaultHeartBeatInterval is the heartbeat cadence of battery powered devices.
+const defaultHeartBeatInterval = time.Hour
+
+// heartBeatIntervals are the heartbeat cadences of the models not sending heartbeats hourly.
+var heartBeatIntervals = map[string]time.Duration{
+	"gateway":       10 * time.Second,
+	"plug":          10 * time.Minute,
+	"ctrl_neutral1": 10 * time.Minute,
+	"ctrl_neutral2": 10 * time.Minute,
+	"ctrl_ln1":      10 * time.Minute,
+	"ctrl_ln2":      10 * time.Minute,
+	"natgas":        10 * time.Minute,
+}
+
+// common holds the state every child device has: identity, last update time and liveness.
+type common struct {
+	sid               string
+	model             string
+	gateway           string
+	heartBeatInterval time.Duration
+	lastUpdateAt      atomic.Pointer[time.Time]
+	notifiedLiveness  atomic.Int32
+}
+
+func (c *common) init(sid string, model string, gateway string) {
+	c.sid = sid
+	c.model = model
+	c.gateway = gateway
+
+	c.heartBeatInterval = defaultHeartBeatInterval
+	if interval, fnd := heartBeatIntervals[model]; fnd {
+		c.heartBeatInterval = interval
+	}
+}
+
+func (c *common) SID() string {
+	return c.sid
+}
+
+func (c *common) Model() string {
+	return c.model
+}
+
+func (c *common) Gateway() string {
+	return c.gateway
+}
+
+func (c *common) LastUpdateAt() time.Time {
+	ptr := c.lastUpdateAt.Load()
+	if ptr == nil {
+		return time.Time{}
+	}
+
+	return *ptr
+}
+
+func (c *common) HeartBeatInterval() time.Duration {
+	return c.heartBeatInterval
+}
+
+func (c *common) Liveness() devices.Liveness {
+	return devices.LivenessOf(time.Since(c.LastUpdateAt()), c.heartBeatInterval)
+}
+
+// CheckLiveness returns the current liveness and whether it differs from the one of the previous check.
+func (c *common) CheckLiveness() (devices.Liveness, bool) {
+	liveness := c.Liveness()
+	previous := devices.Liveness(c.notifiedLiveness.Swap(int32(liveness)))
+
+	return liveness, liveness != previous
+}
+
+func (c *common) touch(at time.Time) {
+	c.lastUpdateAt.Store(&at)
+}
+
+// battery holds the voltage of battery powered devices.
+type battery struct {
+	voltage atomic.Pointer[float32]
+}
+
+func (b *battery) BatteryVoltage() float32 {
+	ptr := b.voltage.Load()
+	if ptr == nil {
+		return 0
+	}
+
+	return *ptr
+}
+
+func (b *battery) storeVoltage(millivolts *int) {
+	if millivolts == nil {
+		return
+	}
+
+	voltage := float32(*millivolts) / 1000
+	b.voltage.Store(&voltage)
+}
+
+// parseSeconds parses durations the devices report as a string number of seconds.
+func parseSeconds(name string, value string) (time.Duration, error) {
+	seconds, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %s from '%v': %w", name, value, err)
+	}
+
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// calibrated holds the calibration of a device measuring temperature, humidity or pressure.
+type calibrated struct {
+	calibration atomic.Pointer[devices.Calibration]
+}
+
+func (c *calibrated) Calibration() devices.Calibration {
+	ptr := c.calibration.Load()
+	if ptr == nil {
+		return devices.Calibration{}
+	}
+
+	return *ptr
+}
+
+func (c *calibrated) SetCalibration(calibration devices.Calibration) {
+	c.calibration.Store(&calibration)
+}
@@ -0,0 +1,50 @@
+package device
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cherserver/infocenter/service/devices"
+)
+
+func TestLiveness(t *testing.T) {
+	var c common
+	c.init("158d0001fd4989", "sensor_ht", "test")
+	require.Equal(t, time.Hour, c.HeartBeatInterval())
+
+	c.touch(time.Now())
+	liveness, changed := c.CheckLiveness()
+	require.Equal(t, devices.Online, liveness)
+	require.False(t, changed)
+
+	c.touch(time.Now().Add(-100 * time.Minute))
+	liveness, changed = c.CheckLiveness()
+	require.Equal(t, devices.Stale, liveness)
+	require.True(t, changed)
+
+	_, changed = c.CheckLiveness()
+	require.False(t, changed)
+
+	c.touch(time.Now().Add(-4 * time.Hour))
+	liveness, changed = c.CheckLiveness()
+	require.Equal(t, devices.Offline, liveness)
+	require.True(t, changed)
+
+	c.touch(time.Now())
+	liveness, changed = c.CheckLiveness()
+	require.Equal(t, devices.Online, liveness)
+	require.True(t, changed)
+}
+
+func TestGatewayLiveness(t *testing.T) {
+	var c common
+	c.init("34ce00fa5c2e", "gateway", "test")
+
+	c.touch(time.Now().Add(-20 * time.Second))
+	require.Equal(t, devices.Stale, c.Liveness())
+
+	c.touch(time.Now().Add(-31 * time.Second))
+	require.Equal(t, devices.Offline, c.Liveness())
+}
@@ -0,0 +1,128 @@
+package device
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cherserver/infocenter/service/devices"
+	"github.com/cherserver/infocenter/service/devices/xiaomi/transport"
+)
+
+func init() {
+	registerModels(NewCube, "cube", "sensor_cube.aqgl01")
+}
+
+var cubeEvents = map[string]devices.EventKind{
+	"flip90":    devices.EventFlip90,
+	"flip180":   devices.EventFlip180,
+	"move":      devices.EventMove,
+	"tap_twice": devices.EventTapTwice,
+	"shake_air": devices.EventShakeAir,
+	"swing":     devices.EventSwing,
+	"alert":     devices.EventAlert,
+	"free_fall": devices.EventFreeFall,
+}
+
+var (
+	_ devices.Device         = &Cube{}
+	_ devices.GatewayChild   = &Cube{}
+	_ devices.BatteryPowered = &Cube{}
+	_ devices.EventSource    = &Cube{}
+)
+
+type cubeData struct {
+	Voltage *int    `json:"voltage"`
+	Status  *string `json:"status"`
+	Rotate  *string `json:"rotate"`
+}
+
+// Cube is a "cube" or "sensor_cube.aqgl01" controller, its gestures are reported as events.
+type Cube struct {
+	common
+	battery
+	emitter
+}
+
+func NewCube(gateway *transport.Transport, sid string, model string, initData string) (*Cube, error) {
+	sensor := &Cube{}
+	sensor.init(sid, model, gateway.Name())
+
+	err := sensor.parseData(initData, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %v with sid '%v', can't parse init data: %w", model, sid, err)
+	}
+
+	gateway.RegisterHeartBeatConsumer(sensor.SID(), sensor.OnHeartBeat)
+	gateway.RegisterReportConsumer(sensor.SID(), sensor.OnReport)
+
+	return sensor, nil
+}
+
+// OnHeartBeat doesn't emit events, the heartbeat repeats the last gesture.
+func (s *Cube) OnHeartBeat(data string) {
+	_ = s.parseData(data, false)
+}
+
+func (s *Cube) OnReport(data string) {
+	_ = s.parseData(data, true)
+}
+
+func (s *Cube) parseData(data string, report bool) error {
+	var parsedData cubeData
+	err := json.Unmarshal([]byte(data), &parsedData)
+	if err != nil {
+		return fmt.Errorf("failed to parse data: %w", err)
+	}
+
+	now := time.Now()
+	s.storeVoltage(parsedData.Voltage)
+
+	event := devices.Event{
+		SID:     s.sid,
+		Model:   s.model,
+		Gateway: s.gateway,
+		At:      now,
+	}
+
+	if parsedData.Status != nil {
+		var fnd bool
+		event.Kind, fnd = cubeEvents[*parsedData.Status]
+		if !fnd {
+			return fmt.Errorf("failed to parse status from '%v'", *parsedData.Status)
+		}
+	}
+
+	if parsedData.Rotate != nil {
+		event.Kind = devices.EventRotate
+		event.Angle, err = parseRotation(*parsedData.Rotate)
+		if err != nil {
+			return err
+		}
+	}
+
+	s.touch(now)
+
+	if report && event.Kind != "" {
+		log.Printf("New '%s' device event '%v', angle '%v'", s.sid, event.Kind, event.Angle)
+
+		s.emit(event)
+	}
+
+	return nil
+}
+
+// parseRotation parses "angle" or "angle,duration" the cube reports rotation with.
+func parseRotation(value string) (int, error) {
+	angleValue, _, _ := strings.Cut(value, ",")
+
+	angle, err := strconv.Atoi(angleValue)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse rotate from '%v': %w", value, err)
+	}
+
+	return angle, nil
+}
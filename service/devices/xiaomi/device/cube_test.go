@@ -0,0 +1,61 @@
+package device
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cherserver/infocenter/service/devices"
+)
+
+func TestCubeEvents(t *testing.T) {
+	cube, err := NewCube(newTestTransport(t), "158d000101a3c9", "cube", `{"voltage":3105,"status":"flip90"}`)
+	require.NoError(t, err)
+	require.InDelta(t, 3.105, cube.BatteryVoltage(), 0.0001)
+
+	_, fnd := cube.LastEvent()
+	require.False(t, fnd, "init data must not produce events")
+
+	events := make([]devices.Event, 0)
+	cube.RegisterEventConsumer(func(event devices.Event) {
+		events = append(events, event)
+	})
+
+	cube.OnHeartBeat(`{"voltage":3095,"status":"flip90"}`)
+	require.Empty(t, events)
+
+	cube.OnReport(`{"status":"flip180"}`)
+	cube.OnReport(`{"rotate":"-30,500"}`)
+	cube.OnReport(`{"rotate":"45"}`)
+	cube.OnReport(`{"status":"tumble"}`)
+
+	require.Len(t, events, 3)
+	require.Equal(t, devices.EventFlip180, events[0].Kind)
+	require.Equal(t, "158d000101a3c9", events[0].SID)
+	require.Equal(t, "test", events[0].Gateway)
+	require.Equal(t, devices.EventRotate, events[1].Kind)
+	require.Equal(t, -30, events[1].Angle)
+	require.Equal(t, 45, events[2].Angle)
+
+	last, fnd := cube.LastEvent()
+	require.True(t, fnd)
+	require.Equal(t, events[2], last)
+}
+
+func TestButtonEvents(t *testing.T) {
+	button, err := NewButton(newTestTransport(t), "158d00012f6b1c", "sensor_switch.aq2", `{"voltage":3025}`)
+	require.NoError(t, err)
+
+	kinds := make([]devices.EventKind, 0)
+	button.RegisterEventConsumer(func(event devices.Event) {
+		kinds = append(kinds, event.Kind)
+	})
+
+	button.OnReport(`{"status":"click"}`)
+	button.OnHeartBeat(`{"voltage":3015,"status":"click"}`)
+	button.OnReport(`{"status":"double_click"}`)
+	button.OnReport(`{"status":"long_click_press"}`)
+
+	require.Equal(t, []devices.EventKind{devices.EventClick, devices.EventDoubleClick, devices.EventLongClickPress}, kinds)
+	require.InDelta(t, 3.015, button.BatteryVoltage(), 0.0001)
+}
@@ -0,0 +1,161 @@
+package device
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/cherserver/infocenter/service/devices"
+	"github.com/cherserver/infocenter/service/devices/xiaomi/transport"
+)
+
+// Alarm codes of smoke and natural gas detectors.
+const (
+	detectorAlarmReleased         = "0"
+	detectorAlarmRaised           = "1"
+	detectorAlarmSelfTest         = "2"
+	detectorAlarmBatteryFault     = "8"
+	detectorAlarmSensitivityFault = "64"
+	detectorAlarmCommFault        = "32768"
+)
+
+func init() {
+	registerModel("natgas", NewNatGas)
+	registerModel("smoke", NewSmoke)
+}
+
+var (
+	_ devices.Device        = &Detector{}
+	_ devices.GatewayChild  = &Detector{}
+	_ devices.AlarmSensor   = &Detector{}
+	_ devices.DensitySensor = &Detector{}
+
+	_ devices.AlarmSensor    = &SmokeDetector{}
+	_ devices.BatteryPowered = &SmokeDetector{}
+)
+
+type detectorData struct {
+	Voltage *int    `json:"voltage"`
+	Alarm   *string `json:"alarm"`
+	Density *string `json:"density"`
+}
+
+// Detector is a mains powered "natgas" detector.
+type Detector struct {
+	common
+	alarm
+	density atomic.Pointer[float32]
+}
+
+// SmokeDetector is a battery powered "smoke" detector.
+type SmokeDetector struct {
+	Detector
+	battery
+}
+
+func NewNatGas(gateway *transport.Transport, sid string, initData string) (*Detector, error) {
+	sensor := &Detector{}
+	sensor.init(sid, "natgas", gateway.Name())
+
+	err := sensor.parseData(initData, false, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create natgas with sid '%v', can't parse init data: %w", sid, err)
+	}
+
+	gateway.RegisterHeartBeatConsumer(sensor.SID(), sensor.OnHeartBeat)
+	gateway.RegisterReportConsumer(sensor.SID(), sensor.OnReport)
+
+	return sensor, nil
+}
+
+func NewSmoke(gateway *transport.Transport, sid string, initData string) (*SmokeDetector, error) {
+	sensor := &SmokeDetector{}
+	sensor.init(sid, "smoke", gateway.Name())
+
+	err := sensor.parseData(initData, false, sensor.storeVoltage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create smoke with sid '%v', can't parse init data: %w", sid, err)
+	}
+
+	gateway.RegisterHeartBeatConsumer(sensor.SID(), sensor.OnHeartBeat)
+	gateway.RegisterReportConsumer(sensor.SID(), sensor.OnReport)
+
+	return sensor, nil
+}
+
+func (s *Detector) OnHeartBeat(data string) {
+	_ = s.parseData(data, false, nil)
+}
+
+func (s *Detector) OnReport(data string) {
+	_ = s.parseData(data, true, nil)
+}
+
+func (s *SmokeDetector) OnHeartBeat(data string) {
+	_ = s.parseData(data, false, s.storeVoltage)
+}
+
+func (s *SmokeDetector) OnReport(data string) {
+	_ = s.parseData(data, true, s.storeVoltage)
+}
+
+func (s *Detector) Density() float32 {
+	ptr := s.density.Load()
+	if ptr == nil {
+		return 0
+	}
+
+	return *ptr
+}
+
+func (s *Detector) parseData(data string, isReport bool, storeVoltage func(millivolts *int)) error {
+	var parsedData detectorData
+	err := json.Unmarshal([]byte(data), &parsedData)
+	if err != nil {
+		return fmt.Errorf("failed to parse data: %w", err)
+	}
+
+	now := time.Now()
+	if storeVoltage != nil {
+		storeVoltage(parsedData.Voltage)
+	}
+
+	if parsedData.Alarm != nil {
+		switch *parsedData.Alarm {
+		case detectorAlarmReleased:
+			s.storeAlarm(false, isReport, now)
+		case detectorAlarmRaised:
+			s.storeAlarm(true, isReport, now)
+		case detectorAlarmSelfTest:
+			log.Printf("Device '%s' self-test", s.sid)
+			s.storeSelfTest(now)
+		case detectorAlarmBatteryFault:
+			log.Printf("Device '%s' reports battery fault", s.sid)
+		case detectorAlarmSensitivityFault:
+			log.Printf("Device '%s' reports sensitivity fault", s.sid)
+		case detectorAlarmCommFault:
+			log.Printf("Device '%s' reports communication fault", s.sid)
+		default:
+			return fmt.Errorf("failed to parse alarm from '%v'", *parsedData.Alarm)
+		}
+	}
+
+	if parsedData.Density != nil {
+		density, err := strconv.ParseInt(*parsedData.Density, 10, 32)
+		if err != nil {
+			return fmt.Errorf("failed to parse density from '%v': %w", *parsedData.Density, err)
+		}
+
+		floatDensity := float32(density)
+		s.density.Store(&floatDensity)
+	}
+
+	log.Printf("New '%s' device data: alarm '%v', density '%v'", s.sid, s.Alarm(), s.Density())
+
+	s.touch(now)
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package device
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNatGasParsing(t *testing.T) {
+	sensor, err := NewNatGas(newTestTransport(t), "158d0001b8c34e", `{"alarm":"0","density":"0"}`)
+	require.NoError(t, err)
+	require.Equal(t, "natgas", sensor.Model())
+	require.Equal(t, 10*time.Minute, sensor.HeartBeatInterval())
+	require.False(t, sensor.Alarm())
+	require.Zero(t, sensor.Density())
+	require.True(t, sensor.AlarmChangedAt().IsZero())
+
+	sensor.OnReport(`{"alarm":"1"}`)
+	require.True(t, sensor.Alarm())
+	require.WithinDuration(t, time.Now(), sensor.AlarmChangedAt(), time.Second)
+
+	sensor.OnReport(`{"density":"340"}`)
+	require.InDelta(t, 340, sensor.Density(), 0.0001)
+
+	sensor.OnReport(`{"alarm":"0"}`)
+	require.False(t, sensor.Alarm())
+}
+
+func TestNatGasHeartBeatKeepsChangeTime(t *testing.T) {
+	sensor, err := NewNatGas(newTestTransport(t), "158d0001b8c34e", `{"alarm":"0"}`)
+	require.NoError(t, err)
+
+	// the state sent by heartbeat may have changed any time before
+	sensor.OnHeartBeat(`{"alarm":"1","density":"120"}`)
+	require.True(t, sensor.Alarm())
+	require.True(t, sensor.AlarmChangedAt().IsZero())
+	require.InDelta(t, 120, sensor.Density(), 0.0001)
+}
+
+func TestSmokeParsing(t *testing.T) {
+	sensor, err := NewSmoke(newTestTransport(t), "158d0001d8e7a1", `{"voltage":3055,"alarm":"0"}`)
+	require.NoError(t, err)
+	require.Equal(t, "smoke", sensor.Model())
+	require.InDelta(t, 3.055, sensor.BatteryVoltage(), 0.0001)
+	require.True(t, sensor.LastSelfTestAt().IsZero())
+
+	sensor.OnReport(`{"alarm":"2"}`)
+	require.False(t, sensor.Alarm())
+	require.WithinDuration(t, time.Now(), sensor.LastSelfTestAt(), time.Second)
+
+	// faults are logged only and don't change the alarm
+	for _, code := range []string{"8", "64", "32768"} {
+		sensor.OnReport(`{"alarm":"` + code + `"}`)
+		require.False(t, sensor.Alarm())
+	}
+
+	sensor.OnReport(`{"alarm":"1","density":"12"}`)
+	require.True(t, sensor.Alarm())
+	require.InDelta(t, 12, sensor.Density(), 0.0001)
+
+	sensor.OnHeartBeat(`{"voltage":2985,"alarm":"1"}`)
+	require.InDelta(t, 2.985, sensor.BatteryVoltage(), 0.0001)
+}
+
+func TestDetectorInvalidData(t *testing.T) {
+	_, err := NewNatGas(newTestTransport(t), "158d0001b8c34e", `{"alarm":"3"}`)
+	require.ErrorContains(t, err, "failed to parse alarm from '3'")
+
+	_, err = NewSmoke(newTestTransport(t), "158d0001d8e7a1", `{"density":"high"}`)
+	require.ErrorContains(t, err, "failed to parse density from 'high'")
+}
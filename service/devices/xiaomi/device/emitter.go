@@ -0,0 +1,43 @@
+package device
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/cherserver/infocenter/service/devices"
+)
+
+// emitter delivers events of a device to the registered consumers.
+type emitter struct {
+	consumers      []devices.EventConsumeFunc
+	consumersMutex sync.Mutex
+	lastEvent      atomic.Pointer[devices.Event]
+}
+
+func (e *emitter) RegisterEventConsumer(consumeFunc devices.EventConsumeFunc) {
+	e.consumersMutex.Lock()
+	defer e.consumersMutex.Unlock()
+
+	e.consumers = append(e.consumers, consumeFunc)
+}
+
+func (e *emitter) LastEvent() (devices.Event, bool) {
+	ptr := e.lastEvent.Load()
+	if ptr == nil {
+		return devices.Event{}, false
+	}
+
+	return *ptr, true
+}
+
+func (e *emitter) emit(event devices.Event) {
+	e.lastEvent.Store(&event)
+
+	e.consumersMutex.Lock()
+	consumers := append([]devices.EventConsumeFunc(nil), e.consumers...)
+	e.consumersMutex.Unlock()
+
+	for _, consumeFunc := range consumers {
+		consumeFunc(event)
+	}
+}
@@ -0,0 +1,85 @@
+package device
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/cherserver/infocenter/service/devices"
+	"github.com/cherserver/infocenter/service/devices/xiaomi/transport"
+)
+
+// Constructor creates a device of the model from the data read from the gateway.
+type Constructor func(gateway *transport.Transport, sid string, model string, initData string) (devices.Device, error)
+
+var (
+	constructors      = make(map[string]Constructor)
+	constructorsMutex sync.RWMutex
+)
+
+// Register makes the constructor create devices of the models, replacing the previously registered one.
+// Device types register themselves on init, tests may register fakes.
+func Register(constructor Constructor, models ...string) {
+	constructorsMutex.Lock()
+	defer constructorsMutex.Unlock()
+
+	for _, model := range models {
+		constructors[model] = constructor
+	}
+}
+
+// Models returns the sorted models having a constructor registered.
+func Models() []string {
+	constructorsMutex.RLock()
+	defer constructorsMutex.RUnlock()
+
+	models := make([]string, 0, len(constructors))
+	for model := range constructors {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+
+	return models
+}
+
+// New creates a device of the model, devices of unknown models are created as Raw.
+func New(gateway *transport.Transport, sid string, model string, initData string) (devices.Device, error) {
+	constructorsMutex.RLock()
+	constructor, fnd := constructors[model]
+	constructorsMutex.RUnlock()
+
+	if !fnd {
+		constructor = newRawDevice
+	}
+
+	dev, err := constructor(gateway, sid, model, initData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %v: %w", model, err)
+	}
+
+	return dev, nil
+}
+
+// registerModel registers the constructor of the device type supporting a single model.
+func registerModel[T devices.Device](model string, create func(gateway *transport.Transport, sid string, initData string) (T, error)) {
+	Register(func(gateway *transport.Transport, sid string, _ string, initData string) (devices.Device, error) {
+		dev, err := create(gateway, sid, initData)
+		if err != nil {
+			return nil, err
+		}
+
+		return dev, nil
+	}, model)
+}
+
+// registerModels registers the constructor of the device type supporting several models.
+func registerModels[T devices.Device](create func(gateway *transport.Transport, sid string, model string, initData string) (T, error), models ...string) {
+	Register(func(gateway *transport.Transport, sid string, model string, initData string) (devices.Device, error) {
+		dev, err := create(gateway, sid, model, initData)
+		if err != nil {
+			return nil, err
+		}
+
+		return dev, nil
+	}, models...)
+}
@@ -0,0 +1,45 @@
+package device
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cherserver/infocenter/service/devices"
+	"github.com/cherserver/infocenter/service/devices/xiaomi/transport"
+)
+
+func TestNewRegisteredModel(t *testing.T) {
+	require.Contains(t, Models(), "sensor_ht")
+	require.Contains(t, Models(), "ctrl_neutral2")
+
+	dev, err := New(newTestTransport(t), "158d0001fd4989", "sensor_ht", `{"voltage":3005,"temperature":"2150"}`)
+	require.NoError(t, err)
+	require.IsType(t, &SensorHT{}, dev)
+
+	_, err = New(newTestTransport(t), "158d0001fd4989", "sensor_ht", `{"temperature":"warm"}`)
+	require.ErrorContains(t, err, "failed to create sensor_ht")
+}
+
+func TestNewFakeModel(t *testing.T) {
+	Register(func(gateway *transport.Transport, sid string, model string, initData string) (devices.Device, error) {
+		return nil, errors.New("fake")
+	}, "test.fake")
+
+	_, err := New(newTestTransport(t), "158d0000000001", "test.fake", `{}`)
+	require.ErrorContains(t, err, "fake")
+}
+
+func TestNewUnknownModel(t *testing.T) {
+	dev, err := New(newTestTransport(t), "158d0000000002", "vibration", `{"voltage":3100,"status":"tilt"}`)
+	require.NoError(t, err)
+	require.Equal(t, "vibration", dev.Model())
+
+	raw, ok := dev.(devices.RawDevice)
+	require.True(t, ok)
+	require.JSONEq(t, `{"voltage":3100,"status":"tilt"}`, string(raw.RawData()))
+
+	dev.(*Raw).OnReport(`{"status":"vibrate","bed_activity":"2"}`)
+	require.JSONEq(t, `{"voltage":3100,"status":"vibrate","bed_activity":"2"}`, string(raw.RawData()))
+}
@@ -0,0 +1,136 @@
+package device
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/cherserver/infocenter/service/devices"
+	"github.com/cherserver/infocenter/service/devices/xiaomi/transport"
+)
+
+const maxBrightness = 100
+
+func init() {
+	registerModel("gateway", NewHub)
+}
+
+var (
+	_ devices.Device          = &Hub{}
+	_ devices.GatewayChild    = &Hub{}
+	_ devices.Illuminometer   = &Hub{}
+	_ devices.RGBLight        = &Hub{}
+	_ devices.ReadingNotifier = &Hub{}
+)
+
+type hubData struct {
+	RGB          *uint32 `json:"rgb"`
+	Illumination *int    `json:"illumination"`
+}
+
+// Hub is the "gateway" itself: its illumination sensor and RGB night light.
+// The light state is one number, brightness in the highest byte and the color in the lower ones.
+type Hub struct {
+	common
+	reporter
+	gateway     *transport.Transport
+	illuminance atomic.Pointer[float32]
+	light       atomic.Uint32
+}
+
+func NewHub(gateway *transport.Transport, sid string, initData string) (*Hub, error) {
+	sensor := &Hub{
+		gateway: gateway,
+	}
+	sensor.init(sid, "gateway", gateway.Name())
+
+	var zero float32 = 0
+	sensor.illuminance.Store(&zero)
+
+	err := sensor.parseData(initData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gateway with sid '%v', can't parse init data: %w", sid, err)
+	}
+
+	gateway.RegisterHeartBeatConsumer(sensor.SID(), sensor.OnHeartBeat)
+	gateway.RegisterReportConsumer(sensor.SID(), sensor.OnReport)
+
+	return sensor, nil
+}
+
+func (s *Hub) OnHeartBeat(data string) {
+	_ = s.parseData(data)
+}
+
+func (s *Hub) OnReport(data string) {
+	_ = s.parseData(data)
+}
+
+func (s *Hub) Illuminance() float32 {
+	return *s.illuminance.Load()
+}
+
+// ReplayReadings reports the current illuminance again, for the consumers registered after the init data is parsed.
+func (s *Hub) ReplayReadings() {
+	s.replay(s.sid, func(devices.Metric) float32 {
+		return s.Illuminance()
+	})
+}
+
+func (s *Hub) RGB() uint32 {
+	return s.light.Load() & 0xFFFFFF
+}
+
+func (s *Hub) Brightness() uint8 {
+	return uint8(s.light.Load() >> 24)
+}
+
+func (s *Hub) SetLight(rgb uint32, brightness uint8) error {
+	if rgb > 0xFFFFFF {
+		return fmt.Errorf("color %X is out of range [0, FFFFFF]", rgb)
+	}
+
+	if brightness > maxBrightness {
+		return fmt.Errorf("brightness %v is out of range [0, %d]", brightness, maxBrightness)
+	}
+
+	resp, err := s.gateway.RequestWriteDevice(s.sid, map[string]interface{}{
+		"rgb": uint32(brightness)<<24 | rgb,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set gateway '%v' light: %w", s.gateway.Name(), err)
+	}
+
+	return s.parseData(resp.Data)
+}
+
+func (s *Hub) parseData(data string) error {
+	var parsedData hubData
+	err := json.Unmarshal([]byte(data), &parsedData)
+	if err != nil {
+		return fmt.Errorf("failed to parse data: %w", err)
+	}
+
+	if parsedData.RGB != nil {
+		s.light.Store(*parsedData.RGB)
+	}
+
+	if parsedData.Illumination != nil {
+		illuminance := float32(*parsedData.Illumination)
+		s.illuminance.Store(&illuminance)
+	}
+
+	log.Printf("New '%s' device data: illuminance '%v', rgb '%06X', brightness '%v'",
+		s.sid, s.Illuminance(), s.RGB(), s.Brightness())
+
+	now := time.Now()
+	s.touch(now)
+
+	if parsedData.Illumination != nil {
+		s.report(s.sid, devices.MetricIlluminance, s.Illuminance(), now)
+	}
+
+	return nil
+}
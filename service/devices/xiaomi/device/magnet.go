@@ -0,0 +1,132 @@
+package device
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/cherserver/infocenter/service/devices"
+	"github.com/cherserver/infocenter/service/devices/xiaomi/transport"
+)
+
+const (
+	magnetStatusOpen  = "open"
+	magnetStatusClose = "close"
+)
+
+func init() {
+	registerModels(NewMagnet, "magnet", "sensor_magnet.aq2")
+}
+
+var (
+	_ devices.Device         = &Magnet{}
+	_ devices.GatewayChild   = &Magnet{}
+	_ devices.BatteryPowered = &Magnet{}
+	_ devices.ContactSensor  = &Magnet{}
+)
+
+type magnetData struct {
+	Voltage *int    `json:"voltage"`
+	Status  *string `json:"status"`
+	NoClose *string `json:"no_close"`
+}
+
+// Magnet is a door/window sensor, both "magnet" and "sensor_magnet.aq2" models.
+type Magnet struct {
+	common
+	battery
+	open         atomic.Bool
+	lastChangeAt atomic.Pointer[time.Time]
+	openFor      atomic.Int64
+}
+
+func NewMagnet(gateway *transport.Transport, sid string, model string, initData string) (*Magnet, error) {
+	sensor := &Magnet{}
+	sensor.init(sid, model, gateway.Name())
+
+	err := sensor.parseData(initData, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %v with sid '%v', can't parse init data: %w", model, sid, err)
+	}
+
+	gateway.RegisterHeartBeatConsumer(sensor.SID(), sensor.OnHeartBeat)
+	gateway.RegisterReportConsumer(sensor.SID(), sensor.OnReport)
+
+	return sensor, nil
+}
+
+func (s *Magnet) OnHeartBeat(data string) {
+	_ = s.parseData(data, false)
+}
+
+func (s *Magnet) OnReport(data string) {
+	_ = s.parseData(data, true)
+}
+
+func (s *Magnet) Open() bool {
+	return s.open.Load()
+}
+
+func (s *Magnet) LastChangeAt() time.Time {
+	ptr := s.lastChangeAt.Load()
+	if ptr == nil {
+		return time.Time{}
+	}
+
+	return *ptr
+}
+
+func (s *Magnet) OpenFor() time.Duration {
+	return time.Duration(s.openFor.Load())
+}
+
+// parseData updates the state, only reports mark open/close changes as they happen at the time of receiving.
+func (s *Magnet) parseData(data string, isReport bool) error {
+	var parsedData magnetData
+	err := json.Unmarshal([]byte(data), &parsedData)
+	if err != nil {
+		return fmt.Errorf("failed to parse data: %w", err)
+	}
+
+	now := time.Now()
+	s.storeVoltage(parsedData.Voltage)
+
+	if parsedData.Status != nil {
+		var open bool
+		switch *parsedData.Status {
+		case magnetStatusOpen:
+			open = true
+		case magnetStatusClose:
+			open = false
+		default:
+			return fmt.Errorf("failed to parse status from '%v'", *parsedData.Status)
+		}
+
+		if s.open.Swap(open) != open && isReport {
+			s.lastChangeAt.Store(&now)
+		}
+
+		if !open {
+			s.openFor.Store(0)
+		}
+	}
+
+	if parsedData.NoClose != nil {
+		openFor, err := parseSeconds("no_close", *parsedData.NoClose)
+		if err != nil {
+			return err
+		}
+
+		s.open.Store(true)
+		s.openFor.Store(int64(openFor))
+	}
+
+	log.Printf("New '%s' device data: open '%v', open for '%v', voltage '%v'",
+		s.sid, s.Open(), s.OpenFor(), s.BatteryVoltage())
+
+	s.touch(now)
+
+	return nil
+}
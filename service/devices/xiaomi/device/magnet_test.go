@@ -0,0 +1,63 @@
+package device
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cherserver/infocenter/service/devices/xiaomi/transport"
+)
+
+func newTestTransport(t *testing.T) *transport.Transport {
+	trans, err := transport.New(transport.Config{
+		Name:    "test",
+		Address: "127.0.0.1",
+		Token:   "0123456789abcdef",
+	})
+	require.NoError(t, err)
+
+	return trans
+}
+
+func TestMagnetParsing(t *testing.T) {
+	sensor, err := NewMagnet(newTestTransport(t), "158d000112fb5d", "magnet", `{"voltage":3035,"status":"close"}`)
+	require.NoError(t, err)
+	require.False(t, sensor.Open())
+	require.Equal(t, "magnet", sensor.Model())
+	require.InDelta(t, 3.035, sensor.BatteryVoltage(), 0.0001)
+	require.True(t, sensor.LastChangeAt().IsZero())
+
+	sensor.OnReport(`{"status":"open"}`)
+	require.True(t, sensor.Open())
+	require.WithinDuration(t, time.Now(), sensor.LastChangeAt(), time.Second)
+	require.Zero(t, sensor.OpenFor())
+
+	sensor.OnReport(`{"no_close":"60"}`)
+	require.True(t, sensor.Open())
+	require.Equal(t, time.Minute, sensor.OpenFor())
+
+	sensor.OnHeartBeat(`{"voltage":3025,"status":"open"}`)
+	require.Equal(t, time.Minute, sensor.OpenFor())
+
+	sensor.OnReport(`{"status":"close"}`)
+	require.False(t, sensor.Open())
+	require.Zero(t, sensor.OpenFor())
+}
+
+func TestMagnetAq2Parsing(t *testing.T) {
+	sensor, err := NewMagnet(newTestTransport(t), "158d0001c18e4a", "sensor_magnet.aq2", `{"voltage":3005,"status":"open"}`)
+	require.NoError(t, err)
+	require.True(t, sensor.Open())
+
+	sensor.OnReport(`{"no_close":"300"}`)
+	require.Equal(t, 5*time.Minute, sensor.OpenFor())
+}
+
+func TestMagnetInvalidData(t *testing.T) {
+	_, err := NewMagnet(newTestTransport(t), "158d000112fb5d", "magnet", `{"status":"ajar"}`)
+	require.ErrorContains(t, err, "failed to parse status from 'ajar'")
+
+	_, err = NewMagnet(newTestTransport(t), "158d000112fb5d", "magnet", `{"no_close":"long"}`)
+	require.ErrorContains(t, err, "failed to parse no_close from 'long'")
+}
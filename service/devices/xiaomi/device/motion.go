@@ -0,0 +1,188 @@
+package device
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/cherserver/infocenter/service/devices"
+	"github.com/cherserver/infocenter/service/devices/xiaomi/transport"
+)
+
+const (
+	motionStatusMotion = "motion"
+
+	// maxOccupancy bounds occupancy when a "no_motion" report is lost, the sensor reports it in 30 minutes at most.
+	maxOccupancy = 30 * time.Minute
+)
+
+func init() {
+	registerModel("motion", NewMotion)
+	registerModel("sensor_motion.aq2", NewMotionAq2)
+}
+
+var (
+	_ devices.Device         = &Motion{}
+	_ devices.GatewayChild   = &Motion{}
+	_ devices.BatteryPowered = &Motion{}
+	_ devices.MotionSensor   = &Motion{}
+
+	_ devices.MotionSensor  = &MotionAq2{}
+	_ devices.Illuminometer = &MotionAq2{}
+)
+
+type motionData struct {
+	Voltage  *int    `json:"voltage"`
+	Status   *string `json:"status"`
+	NoMotion *string `json:"no_motion"`
+	Lux      *string `json:"lux"`
+}
+
+// Motion is a "motion" sensor, occupied since motion is detected until "no_motion" is reported.
+type Motion struct {
+	common
+	battery
+	occupied     atomic.Bool
+	lastMotionAt atomic.Pointer[time.Time]
+	idleFor      atomic.Int64
+}
+
+// MotionAq2 is a "sensor_motion.aq2" sensor, it also measures illuminance.
+type MotionAq2 struct {
+	Motion
+	illuminance atomic.Pointer[float32]
+}
+
+func NewMotion(gateway *transport.Transport, sid string, initData string) (*Motion, error) {
+	sensor := &Motion{}
+	sensor.init(sid, "motion", gateway.Name())
+
+	err := sensor.parseData(initData, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create motion with sid '%v', can't parse init data: %w", sid, err)
+	}
+
+	gateway.RegisterHeartBeatConsumer(sensor.SID(), sensor.OnHeartBeat)
+	gateway.RegisterReportConsumer(sensor.SID(), sensor.OnReport)
+
+	return sensor, nil
+}
+
+func NewMotionAq2(gateway *transport.Transport, sid string, initData string) (*MotionAq2, error) {
+	sensor := &MotionAq2{}
+	sensor.init(sid, "sensor_motion.aq2", gateway.Name())
+
+	var zero float32 = 0
+	sensor.illuminance.Store(&zero)
+
+	err := sensor.parseData(initData, sensor.storeIlluminance)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sensor_motion.aq2 with sid '%v', can't parse init data: %w", sid, err)
+	}
+
+	gateway.RegisterHeartBeatConsumer(sensor.SID(), sensor.OnHeartBeat)
+	gateway.RegisterReportConsumer(sensor.SID(), sensor.OnReport)
+
+	return sensor, nil
+}
+
+func (s *Motion) OnHeartBeat(data string) {
+	_ = s.parseData(data, nil)
+}
+
+func (s *Motion) OnReport(data string) {
+	_ = s.parseData(data, nil)
+}
+
+func (s *MotionAq2) OnHeartBeat(data string) {
+	_ = s.parseData(data, s.storeIlluminance)
+}
+
+func (s *MotionAq2) OnReport(data string) {
+	_ = s.parseData(data, s.storeIlluminance)
+}
+
+func (s *Motion) Occupied() bool {
+	return s.occupied.Load() && time.Since(s.LastMotionAt()) < maxOccupancy
+}
+
+func (s *Motion) LastMotionAt() time.Time {
+	ptr := s.lastMotionAt.Load()
+	if ptr == nil {
+		return time.Time{}
+	}
+
+	return *ptr
+}
+
+func (s *Motion) IdleFor() time.Duration {
+	return time.Duration(s.idleFor.Load())
+}
+
+func (s *MotionAq2) Illuminance() float32 {
+	return *s.illuminance.Load()
+}
+
+func (s *MotionAq2) storeIlluminance(parsedData *motionData) error {
+	if parsedData.Lux == nil {
+		return nil
+	}
+
+	lux, err := strconv.ParseInt(*parsedData.Lux, 10, 32)
+	if err != nil {
+		return fmt.Errorf("failed to parse lux from '%v': %w", *parsedData.Lux, err)
+	}
+
+	floatLux := float32(lux)
+	s.illuminance.Store(&floatLux)
+
+	return nil
+}
+
+func (s *Motion) parseData(data string, parseExtra func(parsedData *motionData) error) error {
+	var parsedData motionData
+	err := json.Unmarshal([]byte(data), &parsedData)
+	if err != nil {
+		return fmt.Errorf("failed to parse data: %w", err)
+	}
+
+	now := time.Now()
+	s.storeVoltage(parsedData.Voltage)
+
+	if parsedData.Status != nil {
+		if *parsedData.Status != motionStatusMotion {
+			return fmt.Errorf("failed to parse status from '%v'", *parsedData.Status)
+		}
+
+		s.occupied.Store(true)
+		s.lastMotionAt.Store(&now)
+		s.idleFor.Store(0)
+	}
+
+	if parsedData.NoMotion != nil {
+		idleFor, err := parseSeconds("no_motion", *parsedData.NoMotion)
+		if err != nil {
+			return err
+		}
+
+		s.occupied.Store(false)
+		s.idleFor.Store(int64(idleFor))
+	}
+
+	if parseExtra != nil {
+		err = parseExtra(&parsedData)
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Printf("New '%s' device data: occupied '%v', idle for '%v', voltage '%v'",
+		s.sid, s.Occupied(), s.IdleFor(), s.BatteryVoltage())
+
+	s.touch(now)
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package device
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMotionParsing(t *testing.T) {
+	sensor, err := NewMotion(newTestTransport(t), "158d0001a4d2b8", `{"voltage":3005}`)
+	require.NoError(t, err)
+	require.Equal(t, "motion", sensor.Model())
+	require.InDelta(t, 3.005, sensor.BatteryVoltage(), 0.0001)
+	require.False(t, sensor.Occupied())
+	require.True(t, sensor.LastMotionAt().IsZero())
+
+	sensor.OnReport(`{"status":"motion"}`)
+	require.True(t, sensor.Occupied())
+	require.WithinDuration(t, time.Now(), sensor.LastMotionAt(), time.Second)
+	require.Zero(t, sensor.IdleFor())
+
+	sensor.OnReport(`{"no_motion":"120"}`)
+	require.False(t, sensor.Occupied())
+	require.Equal(t, 2*time.Minute, sensor.IdleFor())
+
+	sensor.OnHeartBeat(`{"voltage":2995}`)
+	require.False(t, sensor.Occupied())
+	require.InDelta(t, 2.995, sensor.BatteryVoltage(), 0.0001)
+
+	sensor.OnReport(`{"status":"motion"}`)
+	require.True(t, sensor.Occupied())
+	require.Zero(t, sensor.IdleFor())
+}
+
+func TestMotionOccupancyExpiry(t *testing.T) {
+	sensor, err := NewMotion(newTestTransport(t), "158d0001a4d2b8", `{"voltage":3005,"status":"motion"}`)
+	require.NoError(t, err)
+	require.True(t, sensor.Occupied())
+
+	// "no_motion" report is lost
+	lastMotionAt := time.Now().Add(-maxOccupancy - time.Minute)
+	sensor.lastMotionAt.Store(&lastMotionAt)
+	require.False(t, sensor.Occupied())
+
+	sensor.OnReport(`{"status":"motion"}`)
+	require.True(t, sensor.Occupied())
+}
+
+func TestMotionAq2Parsing(t *testing.T) {
+	sensor, err := NewMotionAq2(newTestTransport(t), "158d0001e54b9a", `{"voltage":3035,"lux":"22"}`)
+	require.NoError(t, err)
+	require.Equal(t, "sensor_motion.aq2", sensor.Model())
+	require.False(t, sensor.Occupied())
+	require.InDelta(t, 22, sensor.Illuminance(), 0.0001)
+
+	sensor.OnReport(`{"status":"motion","lux":"35"}`)
+	require.True(t, sensor.Occupied())
+	require.InDelta(t, 35, sensor.Illuminance(), 0.0001)
+
+	sensor.OnReport(`{"no_motion":"300"}`)
+	require.False(t, sensor.Occupied())
+	require.Equal(t, 5*time.Minute, sensor.IdleFor())
+	require.InDelta(t, 35, sensor.Illuminance(), 0.0001)
+
+	sensor.OnHeartBeat(`{"voltage":3025,"lux":"8"}`)
+	require.InDelta(t, 8, sensor.Illuminance(), 0.0001)
+}
+
+func TestMotionInvalidData(t *testing.T) {
+	_, err := NewMotion(newTestTransport(t), "158d0001a4d2b8", `{"status":"still"}`)
+	require.ErrorContains(t, err, "failed to parse status from 'still'")
+
+	_, err = NewMotion(newTestTransport(t), "158d0001a4d2b8", `{"no_motion":"long"}`)
+	require.ErrorContains(t, err, "failed to parse no_motion from 'long'")
+
+	_, err = NewMotionAq2(newTestTransport(t), "158d0001e54b9a", `{"lux":"dark"}`)
+	require.ErrorContains(t, err, "failed to parse lux from 'dark'")
+}
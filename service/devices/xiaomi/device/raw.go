@@ -0,0 +1,90 @@
+package device
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/cherserver/infocenter/service/devices"
+	"github.com/cherserver/infocenter/service/devices/xiaomi/transport"
+)
+
+var (
+	_ devices.Device       = &Raw{}
+	_ devices.GatewayChild = &Raw{}
+	_ devices.RawDevice    = &Raw{}
+)
+
+// Raw is a device of a model with no type of its own, it keeps the last value of every reported field.
+type Raw struct {
+	common
+	data      map[string]json.RawMessage
+	dataMutex sync.Mutex
+}
+
+func NewRaw(gateway *transport.Transport, sid string, model string, initData string) (*Raw, error) {
+	sensor := &Raw{
+		data: make(map[string]json.RawMessage),
+	}
+	sensor.init(sid, model, gateway.Name())
+
+	err := sensor.parseData(initData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %v with sid '%v', can't parse init data: %w", model, sid, err)
+	}
+
+	gateway.RegisterHeartBeatConsumer(sensor.SID(), sensor.OnHeartBeat)
+	gateway.RegisterReportConsumer(sensor.SID(), sensor.OnReport)
+
+	return sensor, nil
+}
+
+func newRawDevice(gateway *transport.Transport, sid string, model string, initData string) (devices.Device, error) {
+	dev, err := NewRaw(gateway, sid, model, initData)
+	if err != nil {
+		return nil, err
+	}
+
+	return dev, nil
+}
+
+func (s *Raw) OnHeartBeat(data string) {
+	_ = s.parseData(data)
+}
+
+func (s *Raw) OnReport(data string) {
+	_ = s.parseData(data)
+}
+
+func (s *Raw) RawData() json.RawMessage {
+	s.dataMutex.Lock()
+	defer s.dataMutex.Unlock()
+
+	data, _ := json.Marshal(s.data)
+	return data
+}
+
+func (s *Raw) parseData(data string) error {
+	parsedData := make(map[string]json.RawMessage)
+	err := json.Unmarshal([]byte(data), &parsedData)
+	if err != nil {
+		return fmt.Errorf("failed to parse data: %w", err)
+	}
+
+	func() {
+		s.dataMutex.Lock()
+		defer s.dataMutex.Unlock()
+
+		for field, value := range parsedData {
+			s.data[field] = value
+		}
+	}()
+
+	log.Printf("New '%s' device of model '%v' raw data: '%s'", s.sid, s.model, data)
+
+	s.touch(time.Now())
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package device
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/cherserver/infocenter/service/devices"
+	"github.com/cherserver/infocenter/service/devices/xiaomi/transport"
+)
+
+const (
+	relayStateOn  = "on"
+	relayStateOff = "off"
+)
+
+// relayFields lists the data fields holding the channel states of every relay model.
+var relayFields = map[string][]string{
+	"plug":          {"status"},
+	"ctrl_neutral1": {"channel_0"},
+	"ctrl_neutral2": {"channel_0", "channel_1"},
+	"ctrl_ln1":      {"channel_0"},
+	"ctrl_ln2":      {"channel_0", "channel_1"},
+}
+
+func init() {
+	for model := range relayFields {
+		registerModels(NewRelay, model)
+	}
+}
+
+var (
+	_ devices.Device       = &Relay{}
+	_ devices.GatewayChild = &Relay{}
+	_ devices.Switchable   = &Relay{}
+)
+
+// Relay is a smart plug or a wall switch with one or two channels.
+type Relay struct {
+	common
+	gateway  *transport.Transport
+	fields   []string
+	channels []atomic.Bool
+}
+
+func NewRelay(gateway *transport.Transport, sid string, model string, initData string) (*Relay, error) {
+	fields, fnd := relayFields[model]
+	if !fnd {
+		return nil, fmt.Errorf("unsupported relay model '%v'", model)
+	}
+
+	sensor := &Relay{
+		gateway:  gateway,
+		fields:   fields,
+		channels: make([]atomic.Bool, len(fields)),
+	}
+	sensor.init(sid, model, gateway.Name())
+
+	err := sensor.parseData(initData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %v with sid '%v', can't parse init data: %w", model, sid, err)
+	}
+
+	gateway.RegisterHeartBeatConsumer(sensor.SID(), sensor.OnHeartBeat)
+	gateway.RegisterReportConsumer(sensor.SID(), sensor.OnReport)
+
+	return sensor, nil
+}
+
+func (s *Relay) OnHeartBeat(data string) {
+	_ = s.parseData(data)
+}
+
+func (s *Relay) OnReport(data string) {
+	_ = s.parseData(data)
+}
+
+func (s *Relay) Channels() int {
+	return len(s.channels)
+}
+
+func (s *Relay) On(channel int) bool {
+	if channel < 0 || channel >= len(s.channels) {
+		return false
+	}
+
+	return s.channels[channel].Load()
+}
+
+func (s *Relay) Switch(channel int, on bool) error {
+	if channel < 0 || channel >= len(s.channels) {
+		return fmt.Errorf("device '%v' has no channel %d", s.sid, channel)
+	}
+
+	state := relayStateOff
+	if on {
+		state = relayStateOn
+	}
+
+	resp, err := s.gateway.RequestWriteDevice(s.sid, map[string]interface{}{
+		s.fields[channel]: state,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to switch device '%v' channel %d %v: %w", s.sid, channel, state, err)
+	}
+
+	return s.parseData(resp.Data)
+}
+
+func (s *Relay) parseData(data string) error {
+	parsedData := make(map[string]interface{})
+	err := json.Unmarshal([]byte(data), &parsedData)
+	if err != nil {
+		return fmt.Errorf("failed to parse data: %w", err)
+	}
+
+	for channel, field := range s.fields {
+		value, fnd := parsedData[field]
+		if !fnd {
+			continue
+		}
+
+		switch value {
+		case relayStateOn:
+			s.channels[channel].Store(true)
+		case relayStateOff:
+			s.channels[channel].Store(false)
+		default:
+			return fmt.Errorf("failed to parse %s from '%v'", field, value)
+		}
+	}
+
+	states := make([]bool, len(s.channels))
+	for channel := range s.channels {
+		states[channel] = s.channels[channel].Load()
+	}
+
+	log.Printf("New '%s' device data: channels '%v'", s.sid, states)
+
+	s.touch(time.Now())
+
+	return nil
+}
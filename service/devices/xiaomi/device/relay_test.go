@@ -0,0 +1,97 @@
+package device
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cherserver/infocenter/service/devices/xiaomi/internal/simtest"
+	"github.com/cherserver/infocenter/service/devices/xiaomi/simulator"
+	"github.com/cherserver/infocenter/service/devices/xiaomi/transport"
+)
+
+const testSwitchSID = "158d0001a5c7e2"
+
+// newSimulatedTransport connects to a gateway simulator with a two channels wall switch.
+func newSimulatedTransport(t *testing.T) (*simulator.Simulator, *transport.Transport) {
+	sim := simtest.Start(t, simulator.Config{
+		Devices: []simulator.Device{
+			{
+				SID:   testSwitchSID,
+				Model: "ctrl_neutral2",
+				Data:  map[string]interface{}{"channel_0": "off", "channel_1": "on"},
+			},
+		},
+	})
+
+	trans, err := transport.New(transport.Config{
+		Name:           "test",
+		Address:        "127.0.0.1",
+		Port:           sim.Addr().Port,
+		Token:          simtest.Token,
+		EventsAddress:  sim.EventsAddr().String(),
+		RequestTimeout: 200 * time.Millisecond,
+		Retry:          transport.RetryPolicy{Attempts: 1},
+	})
+	require.NoError(t, err)
+	require.NoError(t, trans.Start())
+	t.Cleanup(func() { _ = trans.Stop() })
+	require.NoError(t, trans.Connect())
+
+	return sim, trans
+}
+
+func TestRelayParsing(t *testing.T) {
+	sensor, err := NewRelay(newTestTransport(t), "158d0001f3a1b2", "plug", `{"status":"on","inuse":"1"}`)
+	require.NoError(t, err)
+	require.Equal(t, 1, sensor.Channels())
+	require.True(t, sensor.On(0))
+	require.False(t, sensor.On(1))
+
+	sensor.OnReport(`{"status":"off"}`)
+	require.False(t, sensor.On(0))
+
+	sensor, err = NewRelay(newTestTransport(t), testSwitchSID, "ctrl_neutral2", `{"channel_0":"on"}`)
+	require.NoError(t, err)
+	require.Equal(t, 2, sensor.Channels())
+	require.True(t, sensor.On(0))
+	require.False(t, sensor.On(1))
+
+	sensor.OnReport(`{"channel_1":"on"}`)
+	require.True(t, sensor.On(0))
+	require.True(t, sensor.On(1))
+
+	sensor.OnHeartBeat(`{"channel_0":"off","channel_1":"off"}`)
+	require.False(t, sensor.On(0))
+	require.False(t, sensor.On(1))
+}
+
+func TestRelayInvalidData(t *testing.T) {
+	_, err := NewRelay(newTestTransport(t), "158d0001f3a1b2", "plug", `{"status":"toggle"}`)
+	require.ErrorContains(t, err, "failed to parse status from 'toggle'")
+
+	_, err = NewRelay(newTestTransport(t), "158d0001f3a1b2", "ctrl_neutral3", `{}`)
+	require.ErrorContains(t, err, "unsupported relay model 'ctrl_neutral3'")
+}
+
+func TestRelaySwitch(t *testing.T) {
+	sim, trans := newSimulatedTransport(t)
+
+	sensor, err := NewRelay(trans, testSwitchSID, "ctrl_neutral2", `{"channel_0":"off","channel_1":"on"}`)
+	require.NoError(t, err)
+
+	require.NoError(t, sensor.Switch(0, true))
+	require.True(t, sensor.On(0))
+	require.True(t, sensor.On(1))
+
+	require.NoError(t, sensor.Switch(1, false))
+	require.True(t, sensor.On(0))
+	require.False(t, sensor.On(1))
+
+	require.ErrorContains(t, sensor.Switch(2, true), "has no channel 2")
+
+	sim.RemoveDevice(testSwitchSID)
+	require.ErrorContains(t, sensor.Switch(0, false), "failed to switch device")
+	require.True(t, sensor.On(0))
+}
@@ -0,0 +1,57 @@
+package device
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cherserver/infocenter/service/devices"
+)
+
+// reporter delivers readings of a device to the registered consumers.
+type reporter struct {
+	consumers      []devices.ReadingConsumeFunc
+	reportedAt     map[devices.Metric]time.Time // when the metrics were reported last
+	consumersMutex sync.Mutex
+}
+
+func (r *reporter) RegisterReadingConsumer(consumeFunc devices.ReadingConsumeFunc) {
+	r.consumersMutex.Lock()
+	defer r.consumersMutex.Unlock()
+
+	r.consumers = append(r.consumers, consumeFunc)
+}
+
+func (r *reporter) report(sid string, metric devices.Metric, value float32, at time.Time) {
+	r.consumersMutex.Lock()
+	consumers := append([]devices.ReadingConsumeFunc(nil), r.consumers...)
+	if r.reportedAt == nil {
+		r.reportedAt = make(map[devices.Metric]time.Time)
+	}
+	r.reportedAt[metric] = at
+	r.consumersMutex.Unlock()
+
+	reading := devices.Reading{
+		SID:    sid,
+		Metric: metric,
+		Value:  value,
+		At:     at,
+	}
+
+	for _, consumeFunc := range consumers {
+		consumeFunc(reading)
+	}
+}
+
+// replay reports the current values of the metrics reported before, stamped with the time they were reported.
+func (r *reporter) replay(sid string, current func(metric devices.Metric) float32) {
+	r.consumersMutex.Lock()
+	reportedAt := make(map[devices.Metric]time.Time, len(r.reportedAt))
+	for metric, at := range r.reportedAt {
+		reportedAt[metric] = at
+	}
+	r.consumersMutex.Unlock()
+
+	for metric, at := range reportedAt {
+		r.report(sid, metric, current(metric), at)
+	}
+}
@@ -12,11 +12,18 @@ import (
 	"github.com/cherserver/infocenter/service/devices/xiaomi/transport"
 )
 
+func init() {
+	registerModel("sensor_ht", NewSensorHT)
+}
+
 var (
-	_ devices.Device         = &SensorHT{}
-	_ devices.BatteryPowered = &SensorHT{}
-	_ devices.Thermometer    = &SensorHT{}
-	_ devices.Hygrometer     = &SensorHT{}
+	_ devices.Device          = &SensorHT{}
+	_ devices.GatewayChild    = &SensorHT{}
+	_ devices.BatteryPowered  = &SensorHT{}
+	_ devices.Thermometer     = &SensorHT{}
+	_ devices.Hygrometer      = &SensorHT{}
+	_ devices.Calibratable    = &SensorHT{}
+	_ devices.ReadingNotifier = &SensorHT{}
 )
 
 type sensorHTData struct {
@@ -26,24 +33,12 @@ type sensorHTData struct {
 }
 
 type SensorHT struct {
-	sid          string
-	lastUpdateAt atomic.Pointer[time.Time]
-	voltage      atomic.Pointer[float32]
-	temperature  atomic.Pointer[float32]
-	humidity     atomic.Pointer[float32]
-}
-
-func (s *SensorHT) SID() string {
-	return s.sid
-}
-
-func (s *SensorHT) LastUpdateAt() time.Time {
-	ptr := s.lastUpdateAt.Load()
-	if ptr == nil {
-		return time.Time{}
-	}
-
-	return *ptr
+	common
+	calibrated
+	reporter
+	voltage     atomic.Pointer[float32]
+	temperature atomic.Pointer[float32]
+	humidity    atomic.Pointer[float32]
 }
 
 func (s *SensorHT) OnHeartBeat(data string) {
@@ -55,9 +50,8 @@ func (s *SensorHT) OnReport(data string) {
 }
 
 func NewSensorHT(gateway *transport.Transport, sid string, initData string) (*SensorHT, error) {
-	sensor := &SensorHT{
-		sid: sid,
-	}
+	sensor := &SensorHT{}
+	sensor.init(sid, "sensor_ht", gateway.Name())
 
 	var zero float32 = 0
 	sensor.voltage.Store(&zero)
@@ -80,11 +74,29 @@ func (s *SensorHT) BatteryVoltage() float32 {
 }
 
 func (s *SensorHT) Temperature() float32 {
-	return *s.temperature.Load()
+	return s.Calibration().Temperature(*s.temperature.Load())
 }
 
 func (s *SensorHT) Humidity() float32 {
-	return *s.humidity.Load()
+	return s.Calibration().Humidity(*s.humidity.Load())
+}
+
+// ReplayReadings reports the current readings again, for the consumers registered after the init data is parsed.
+func (s *SensorHT) ReplayReadings() {
+	s.replay(s.sid, func(metric devices.Metric) float32 {
+		if metric == devices.MetricHumidity {
+			return s.Humidity()
+		}
+
+		return s.Temperature()
+	})
+}
+
+func (s *SensorHT) RawMeasurements() devices.Measurements {
+	return devices.Measurements{
+		Temperature: s.temperature.Load(),
+		Humidity:    s.humidity.Load(),
+	}
 }
 
 func (s *SensorHT) parseData(data string) error {
@@ -122,8 +134,16 @@ func (s *SensorHT) parseData(data string) error {
 	log.Printf("New '%s' device data: temp '%v', hum '%v', voltage '%v'",
 		s.sid, s.Temperature(), s.Humidity(), s.BatteryVoltage())
 
-	newLastUpdateAt := time.Now()
-	s.lastUpdateAt.Store(&newLastUpdateAt)
+	now := time.Now()
+	s.touch(now)
+
+	if parsedData.Temperature != nil {
+		s.report(s.sid, devices.MetricTemperature, s.Temperature(), now)
+	}
+
+	if parsedData.Humidity != nil {
+		s.report(s.sid, devices.MetricHumidity, s.Humidity(), now)
+	}
 
 	return nil
 }
@@ -0,0 +1,90 @@
+package device
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/cherserver/infocenter/service/devices"
+	"github.com/cherserver/infocenter/service/devices/xiaomi/transport"
+)
+
+const (
+	leakStatusLeak   = "leak"
+	leakStatusNoLeak = "no_leak"
+)
+
+func init() {
+	registerModel("sensor_wleak.aq1", NewSensorWleak)
+}
+
+var (
+	_ devices.Device         = &SensorWleak{}
+	_ devices.GatewayChild   = &SensorWleak{}
+	_ devices.BatteryPowered = &SensorWleak{}
+	_ devices.AlarmSensor    = &SensorWleak{}
+)
+
+type sensorWleakData struct {
+	Voltage *int    `json:"voltage"`
+	Status  *string `json:"status"`
+}
+
+// SensorWleak is a "sensor_wleak.aq1" water leak sensor.
+type SensorWleak struct {
+	common
+	battery
+	alarm
+}
+
+func NewSensorWleak(gateway *transport.Transport, sid string, initData string) (*SensorWleak, error) {
+	sensor := &SensorWleak{}
+	sensor.init(sid, "sensor_wleak.aq1", gateway.Name())
+
+	err := sensor.parseData(initData, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sensor_wleak.aq1 with sid '%v', can't parse init data: %w", sid, err)
+	}
+
+	gateway.RegisterHeartBeatConsumer(sensor.SID(), sensor.OnHeartBeat)
+	gateway.RegisterReportConsumer(sensor.SID(), sensor.OnReport)
+
+	return sensor, nil
+}
+
+func (s *SensorWleak) OnHeartBeat(data string) {
+	_ = s.parseData(data, false)
+}
+
+func (s *SensorWleak) OnReport(data string) {
+	_ = s.parseData(data, true)
+}
+
+func (s *SensorWleak) parseData(data string, isReport bool) error {
+	var parsedData sensorWleakData
+	err := json.Unmarshal([]byte(data), &parsedData)
+	if err != nil {
+		return fmt.Errorf("failed to parse data: %w", err)
+	}
+
+	now := time.Now()
+	s.storeVoltage(parsedData.Voltage)
+
+	if parsedData.Status != nil {
+		switch *parsedData.Status {
+		case leakStatusLeak:
+			s.storeAlarm(true, isReport, now)
+		case leakStatusNoLeak:
+			s.storeAlarm(false, isReport, now)
+		default:
+			return fmt.Errorf("failed to parse status from '%v'", *parsedData.Status)
+		}
+	}
+
+	log.Printf("New '%s' device data: leak '%v', voltage '%v'", s.sid, s.Alarm(), s.BatteryVoltage())
+
+	s.touch(now)
+
+	return nil
+}
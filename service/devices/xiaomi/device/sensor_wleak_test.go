@@ -0,0 +1,40 @@
+package device
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSensorWleakParsing(t *testing.T) {
+	sensor, err := NewSensorWleak(newTestTransport(t), "158d0001d5a8b3", `{"voltage":3015,"status":"no_leak"}`)
+	require.NoError(t, err)
+	require.Equal(t, "sensor_wleak.aq1", sensor.Model())
+	require.InDelta(t, 3.015, sensor.BatteryVoltage(), 0.0001)
+	require.False(t, sensor.Alarm())
+	require.True(t, sensor.AlarmChangedAt().IsZero())
+
+	sensor.OnReport(`{"status":"leak"}`)
+	require.True(t, sensor.Alarm())
+	changedAt := sensor.AlarmChangedAt()
+	require.WithinDuration(t, time.Now(), changedAt, time.Second)
+
+	// repeated state is not a change
+	sensor.OnReport(`{"status":"leak"}`)
+	require.True(t, sensor.Alarm())
+	require.Equal(t, changedAt, sensor.AlarmChangedAt())
+
+	sensor.OnReport(`{"status":"no_leak"}`)
+	require.False(t, sensor.Alarm())
+	require.False(t, sensor.AlarmChangedAt().Before(changedAt))
+
+	sensor.OnHeartBeat(`{"voltage":3005}`)
+	require.False(t, sensor.Alarm())
+	require.InDelta(t, 3.005, sensor.BatteryVoltage(), 0.0001)
+}
+
+func TestSensorWleakInvalidData(t *testing.T) {
+	_, err := NewSensorWleak(newTestTransport(t), "158d0001d5a8b3", `{"status":"wet"}`)
+	require.ErrorContains(t, err, "failed to parse status from 'wet'")
+}
@@ -12,12 +12,19 @@ import (
 	"github.com/cherserver/infocenter/service/devices/xiaomi/transport"
 )
 
+func init() {
+	registerModel("weather.v1", NewWeatherV1)
+}
+
 var (
-	_ devices.Device         = &WeatherV1{}
-	_ devices.BatteryPowered = &WeatherV1{}
-	_ devices.Thermometer    = &WeatherV1{}
-	_ devices.Hygrometer     = &WeatherV1{}
-	_ devices.Barometer      = &WeatherV1{}
+	_ devices.Device          = &WeatherV1{}
+	_ devices.GatewayChild    = &WeatherV1{}
+	_ devices.BatteryPowered  = &WeatherV1{}
+	_ devices.Thermometer     = &WeatherV1{}
+	_ devices.Hygrometer      = &WeatherV1{}
+	_ devices.Calibratable    = &WeatherV1{}
+	_ devices.ReadingNotifier = &WeatherV1{}
+	_ devices.Barometer       = &WeatherV1{}
 )
 
 type weatherV1Data struct {
@@ -28,25 +35,13 @@ type weatherV1Data struct {
 }
 
 type WeatherV1 struct {
-	sid          string
-	lastUpdateAt atomic.Pointer[time.Time]
-	voltage      atomic.Pointer[float32]
-	temperature  atomic.Pointer[float32]
-	humidity     atomic.Pointer[float32]
-	pressure     atomic.Pointer[float32]
-}
-
-func (s *WeatherV1) SID() string {
-	return s.sid
-}
-
-func (s *WeatherV1) LastUpdateAt() time.Time {
-	ptr := s.lastUpdateAt.Load()
-	if ptr == nil {
-		return time.Time{}
-	}
-
-	return *ptr
+	common
+	calibrated
+	reporter
+	voltage     atomic.Pointer[float32]
+	temperature atomic.Pointer[float32]
+	humidity    atomic.Pointer[float32]
+	pressure    atomic.Pointer[float32]
 }
 
 func (s *WeatherV1) OnHeartBeat(data string) {
@@ -58,9 +53,8 @@ func (s *WeatherV1) OnReport(data string) {
 }
 
 func NewWeatherV1(gateway *transport.Transport, sid string, initData string) (*WeatherV1, error) {
-	sensor := &WeatherV1{
-		sid: sid,
-	}
+	sensor := &WeatherV1{}
+	sensor.init(sid, "weather.v1", gateway.Name())
 
 	var zero float32 = 0
 	sensor.voltage.Store(&zero)
@@ -84,15 +78,37 @@ func (s *WeatherV1) BatteryVoltage() float32 {
 }
 
 func (s *WeatherV1) Temperature() float32 {
-	return *s.temperature.Load()
+	return s.Calibration().Temperature(*s.temperature.Load())
 }
 
 func (s *WeatherV1) Humidity() float32 {
-	return *s.humidity.Load()
+	return s.Calibration().Humidity(*s.humidity.Load())
 }
 
 func (s *WeatherV1) Pressure() float32 {
-	return *s.pressure.Load()
+	return s.Calibration().Pressure(*s.pressure.Load())
+}
+
+// ReplayReadings reports the current readings again, for the consumers registered after the init data is parsed.
+func (s *WeatherV1) ReplayReadings() {
+	s.replay(s.sid, func(metric devices.Metric) float32 {
+		switch metric {
+		case devices.MetricHumidity:
+			return s.Humidity()
+		case devices.MetricPressure:
+			return s.Pressure()
+		default:
+			return s.Temperature()
+		}
+	})
+}
+
+func (s *WeatherV1) RawMeasurements() devices.Measurements {
+	return devices.Measurements{
+		Temperature: s.temperature.Load(),
+		Humidity:    s.humidity.Load(),
+		Pressure:    s.pressure.Load(),
+	}
 }
 
 func (s *WeatherV1) parseData(data string) error {
@@ -140,8 +156,20 @@ func (s *WeatherV1) parseData(data string) error {
 	log.Printf("New '%s' device data: temp '%v', hum '%v', pressure '%v', voltage '%v'",
 		s.sid, s.Temperature(), s.Humidity(), s.Pressure(), s.BatteryVoltage())
 
-	newLastUpdateAt := time.Now()
-	s.lastUpdateAt.Store(&newLastUpdateAt)
+	now := time.Now()
+	s.touch(now)
+
+	if parsedData.Temperature != nil {
+		s.report(s.sid, devices.MetricTemperature, s.Temperature(), now)
+	}
+
+	if parsedData.Humidity != nil {
+		s.report(s.sid, devices.MetricHumidity, s.Humidity(), now)
+	}
+
+	if parsedData.Pressure != nil {
+		s.report(s.sid, devices.MetricPressure, s.Pressure(), now)
+	}
 
 	return nil
 }
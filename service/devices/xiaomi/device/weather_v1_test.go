@@ -0,0 +1,70 @@
+package device
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cherserver/infocenter/service/devices"
+)
+
+func TestWeatherV1Calibration(t *testing.T) {
+	sensor, err := NewWeatherV1(newTestTransport(t), "158d0001f57fee",
+		`{"voltage":3005,"temperature":"2160","humidity":"9950","pressure":"101325"}`)
+	require.NoError(t, err)
+	require.InDelta(t, 21.6, sensor.Temperature(), 0.0001)
+
+	sensor.SetCalibration(devices.Calibration{
+		TemperatureOffset: -0.6,
+		HumidityOffset:    1,
+		PressureScale:     1.01,
+	})
+	require.InDelta(t, 21, sensor.Temperature(), 0.0001)
+	require.Equal(t, float32(100), sensor.Humidity())
+	require.InDelta(t, 102338.25, sensor.Pressure(), 0.1)
+
+	raw := sensor.RawMeasurements()
+	require.InDelta(t, 21.6, *raw.Temperature, 0.0001)
+	require.InDelta(t, 99.5, *raw.Humidity, 0.0001)
+	require.InDelta(t, 101325, *raw.Pressure, 0.1)
+
+	sensor.SetCalibration(devices.Calibration{})
+	require.InDelta(t, 21.6, sensor.Temperature(), 0.0001)
+}
+
+func TestWeatherV1Readings(t *testing.T) {
+	sensor, err := NewWeatherV1(newTestTransport(t), "158d0001f57fee", `{"voltage":3005}`)
+	require.NoError(t, err)
+	sensor.SetCalibration(devices.Calibration{TemperatureOffset: -0.5})
+
+	var readings []devices.Reading
+	sensor.RegisterReadingConsumer(func(reading devices.Reading) {
+		readings = append(readings, reading)
+	})
+
+	sensor.OnReport(`{"temperature":"2150","pressure":"100900"}`)
+	require.Len(t, readings, 2)
+	require.Equal(t, devices.MetricTemperature, readings[0].Metric)
+	require.Equal(t, float32(21), readings[0].Value)
+	require.Equal(t, devices.MetricPressure, readings[1].Metric)
+	require.Equal(t, float32(100900), readings[1].Value)
+}
+
+func TestWeatherV1ReplayReadings(t *testing.T) {
+	sensor, err := NewWeatherV1(newTestTransport(t), "158d0001f57fee", `{"voltage":3005,"temperature":"2150"}`)
+	require.NoError(t, err)
+	reportedAt := sensor.LastUpdateAt()
+
+	sensor.SetCalibration(devices.Calibration{TemperatureOffset: -0.5})
+
+	var readings []devices.Reading
+	sensor.RegisterReadingConsumer(func(reading devices.Reading) {
+		readings = append(readings, reading)
+	})
+
+	// only the metrics of the init data are replayed, calibrated and stamped with the parsing time
+	sensor.ReplayReadings()
+	require.Equal(t, []devices.Reading{
+		{SID: "158d0001f57fee", Metric: devices.MetricTemperature, Value: 21, At: reportedAt},
+	}, readings)
+}
@@ -0,0 +1,46 @@
+// Package simtest starts gateway simulators for the Xiaomi packages tests.
+package simtest
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cherserver/infocenter/service/devices/xiaomi/simulator"
+)
+
+// Token is the gateway password simulators are started with.
+const Token = "0123456789abcdef"
+
+// Start starts a simulator on a random local port, stopped when the test ends.
+// Events are sent to a free local port when the events address is not set, the password is Token when not set.
+func Start(t *testing.T, cfg simulator.Config) *simulator.Simulator {
+	if cfg.Address == "" {
+		cfg.Address = "127.0.0.1:0"
+	}
+
+	if cfg.EventsAddress == "" {
+		cfg.EventsAddress = FreeUDPAddress(t)
+	}
+
+	if cfg.Password == "" {
+		cfg.Password = Token
+	}
+
+	sim, err := simulator.New(cfg)
+	require.NoError(t, err)
+	require.NoError(t, sim.Start())
+	t.Cleanup(sim.Stop)
+
+	return sim
+}
+
+// FreeUDPAddress returns a local UDP address nothing listens on.
+func FreeUDPAddress(t *testing.T) string {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+
+	return conn.LocalAddr().String()
+}
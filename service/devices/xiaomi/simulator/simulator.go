@@ -0,0 +1,675 @@
+package simulator
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultAddress       = "127.0.0.1:9898"
+	defaultEventsAddress = "224.0.0.50:9898"
+	defaultSID           = "7811dcb25bfe"
+
+	defaultHeartBeatInterval       = 10 * time.Second
+	defaultDeviceHeartBeatInterval = time.Hour
+	defaultReportInterval          = time.Minute
+
+	gatewayModel = "gateway"
+	tokenLength  = 16
+	tokenLetters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+	cmdAckSuffix = "_ack"
+
+	requestWhois           = "whois"
+	requestGetChildDevices = "get_id_list"
+	requestReadDevice      = "read"
+	requestWriteDevice     = "write"
+
+	eventHeartbeat = "heartbeat"
+	eventReport    = "report"
+
+	answerIam    = "iam"
+	protoVersion = "1.1.2"
+)
+
+var initVector = []byte{0x17, 0x99, 0x6d, 0x09, 0x3d, 0x28, 0xdd, 0xb3, 0xba, 0x69, 0x5a, 0x2e, 0x6f, 0x58, 0x56, 0x2e}
+
+// driftingFields are the numeric string fields randomly changed in scheduled reports when drift is enabled.
+var driftingFields = map[string]int{
+	"temperature": 10,
+	"humidity":    50,
+	"pressure":    20,
+}
+
+type Device struct {
+	SID   string                 `yaml:"sid"`
+	Model string                 `yaml:"model"`
+	Data  map[string]interface{} `yaml:"data"`
+}
+
+type Config struct {
+	Address       string `yaml:"address"`        // where requests are listened for, "127.0.0.1:9898" when empty
+	EventsAddress string `yaml:"events_address"` // where events are sent, "224.0.0.50:9898" when empty
+	SID           string `yaml:"sid"`
+
+	// Password is the gateway password write requests are checked against, they are not checked when empty.
+	Password string `yaml:"password"`
+
+	HeartBeatInterval       time.Duration `yaml:"heartbeat_interval"`        // gateway heartbeat, 10 seconds when zero
+	DeviceHeartBeatInterval time.Duration `yaml:"device_heartbeat_interval"` // child devices heartbeat, 1 hour when zero
+	ReportInterval          time.Duration `yaml:"report_interval"`           // child devices report, 1 minute when zero
+
+	// Drift randomly changes temperature, humidity and pressure of the devices in scheduled reports.
+	Drift bool `yaml:"drift"`
+
+	GatewayData map[string]interface{} `yaml:"gateway_data"`
+	Devices     []Device               `yaml:"devices"`
+}
+
+// Request is an incoming gateway request as seen by the simulator.
+type Request struct {
+	Cmd  string
+	SID  string
+	Data string
+}
+
+// Hook intercepts requests before the simulator answers them.
+// When handled is true the reply is sent as is instead of the regular answer, a nil reply is not sent at all.
+type Hook func(req Request) (reply []byte, handled bool)
+
+type message struct {
+	Cmd     string `json:"cmd"`
+	Model   string `json:"model,omitempty"`
+	Sid     string `json:"sid,omitempty"`
+	ShortID int    `json:"short_id,omitempty"`
+	Token   string `json:"token,omitempty"`
+	Data    string `json:"data,omitempty"`
+
+	// "iam" answer fields
+	IP           string `json:"ip,omitempty"`
+	Port         string `json:"port,omitempty"`
+	ProtoVersion string `json:"proto_version,omitempty"`
+}
+
+func New(cfg Config) (*Simulator, error) {
+	if cfg.Address == "" {
+		cfg.Address = defaultAddress
+	}
+
+	if cfg.EventsAddress == "" {
+		cfg.EventsAddress = defaultEventsAddress
+	}
+
+	if cfg.SID == "" {
+		cfg.SID = defaultSID
+	}
+
+	if cfg.Password != "" && len(cfg.Password) != aes.BlockSize {
+		return nil, fmt.Errorf("password must be %d characters long", aes.BlockSize)
+	}
+
+	if cfg.HeartBeatInterval == 0 {
+		cfg.HeartBeatInterval = defaultHeartBeatInterval
+	}
+
+	if cfg.DeviceHeartBeatInterval == 0 {
+		cfg.DeviceHeartBeatInterval = defaultDeviceHeartBeatInterval
+	}
+
+	if cfg.ReportInterval == 0 {
+		cfg.ReportInterval = defaultReportInterval
+	}
+
+	address, err := net.ResolveUDPAddr("udp4", cfg.Address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode address: %w", err)
+	}
+
+	eventsAddress, err := net.ResolveUDPAddr("udp4", cfg.EventsAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode events address: %w", err)
+	}
+
+	sim := &Simulator{
+		cfg:           cfg,
+		address:       address,
+		eventsAddress: eventsAddress,
+		token:         newToken(),
+		devices:       make(map[string]*Device, len(cfg.Devices)),
+		stopped:       make(chan struct{}),
+	}
+
+	gatewayData := cfg.GatewayData
+	if gatewayData == nil {
+		gatewayData = map[string]interface{}{
+			"rgb":          0,
+			"illumination": 300,
+		}
+	}
+
+	sim.gateway = &Device{
+		SID:   cfg.SID,
+		Model: gatewayModel,
+		Data:  gatewayData,
+	}
+
+	for idx := range cfg.Devices {
+		dev := cfg.Devices[idx]
+		if _, fnd := sim.devices[dev.SID]; fnd || dev.SID == cfg.SID {
+			return nil, fmt.Errorf("duplicate device sid '%v'", dev.SID)
+		}
+
+		sim.devices[dev.SID] = &dev
+		sim.order = append(sim.order, dev.SID)
+	}
+
+	return sim, nil
+}
+
+// Simulator speaks the Xiaomi gateway UDP protocol: answers requests, "whois" included, and emits heartbeat
+// and report events.
+type Simulator struct {
+	cfg           Config
+	address       *net.UDPAddr
+	eventsAddress *net.UDPAddr
+
+	conn       *net.UDPConn
+	eventsConn *net.UDPConn
+
+	mutex   sync.Mutex
+	token   string
+	gateway *Device
+	devices map[string]*Device
+	order   []string
+	hook    Hook
+
+	stopped chan struct{}
+	done    sync.WaitGroup
+}
+
+func (s *Simulator) Start() error {
+	var err error
+	s.conn, err = net.ListenUDP("udp4", s.address)
+	if err != nil {
+		return fmt.Errorf("failed to listen UDP: %w", err)
+	}
+
+	s.eventsConn, err = net.DialUDP("udp4", nil, s.eventsAddress)
+	if err != nil {
+		_ = s.conn.Close()
+		return fmt.Errorf("failed to dial events UDP: %w", err)
+	}
+
+	s.done.Add(2)
+	go s.reader()
+	go s.scheduler()
+
+	log.Printf("Gateway simulator '%v' started on %v, events to %v", s.cfg.SID, s.Addr(), s.eventsAddress)
+
+	return nil
+}
+
+func (s *Simulator) Stop() {
+	close(s.stopped)
+	_ = s.conn.Close()
+	s.done.Wait()
+	_ = s.eventsConn.Close()
+
+	log.Printf("Gateway simulator '%v' stopped", s.cfg.SID)
+}
+
+// Addr returns the address requests are listened on.
+func (s *Simulator) Addr() *net.UDPAddr {
+	return s.conn.LocalAddr().(*net.UDPAddr)
+}
+
+// EventsAddr returns the address events are sent to.
+func (s *Simulator) EventsAddr() *net.UDPAddr {
+	return s.eventsAddress
+}
+
+func (s *Simulator) SID() string {
+	return s.cfg.SID
+}
+
+// Token returns the current gateway token, it changes with every gateway heartbeat.
+func (s *Simulator) Token() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.token
+}
+
+// SetHook installs a request hook, nil removes it.
+func (s *Simulator) SetHook(hook Hook) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.hook = hook
+}
+
+// AddDevice pairs a new child device or replaces the existing one with the same SID.
+func (s *Simulator) AddDevice(dev Device) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, fnd := s.devices[dev.SID]; !fnd {
+		s.order = append(s.order, dev.SID)
+	}
+
+	s.devices[dev.SID] = &dev
+}
+
+// RemoveDevice unpairs a child device.
+func (s *Simulator) RemoveDevice(sid string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.devices, sid)
+	for idx, orderSID := range s.order {
+		if orderSID == sid {
+			s.order = append(s.order[:idx], s.order[idx+1:]...)
+			break
+		}
+	}
+}
+
+// Report updates the device state with data and emits a report event.
+func (s *Simulator) Report(sid string, data map[string]interface{}) error {
+	dev, err := s.updateDevice(sid, data)
+	if err != nil {
+		return err
+	}
+
+	return s.sendEvent(eventReport, dev, data)
+}
+
+// HeartBeat emits a heartbeat event of the gateway or a child device with its current state.
+func (s *Simulator) HeartBeat(sid string) error {
+	if sid == s.cfg.SID {
+		return s.gatewayHeartBeat()
+	}
+
+	dev, err := s.updateDevice(sid, nil)
+	if err != nil {
+		return err
+	}
+
+	return s.sendEvent(eventHeartbeat, dev, dev.Data)
+}
+
+// SendRaw emits arbitrary data to the events address, e.g. malformed messages.
+func (s *Simulator) SendRaw(data []byte) error {
+	_, err := s.eventsConn.Write(data)
+	if err != nil {
+		return fmt.Errorf("failed to send event: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Simulator) updateDevice(sid string, data map[string]interface{}) (Device, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	dev := s.findDevice(sid)
+	if dev == nil {
+		return Device{}, fmt.Errorf("unknown device '%v'", sid)
+	}
+
+	if dev.Data == nil {
+		dev.Data = make(map[string]interface{}, len(data))
+	}
+
+	for key, value := range data {
+		dev.Data[key] = value
+	}
+
+	return copyDevice(dev), nil
+}
+
+func (s *Simulator) findDevice(sid string) *Device {
+	if sid == s.cfg.SID {
+		return s.gateway
+	}
+
+	return s.devices[sid]
+}
+
+func (s *Simulator) gatewayHeartBeat() error {
+	s.mutex.Lock()
+	s.token = newToken()
+	token := s.token
+	s.mutex.Unlock()
+
+	data, _ := json.Marshal(map[string]interface{}{
+		"ip": s.Addr().IP.String(),
+	})
+
+	return s.send(s.eventsConn, nil, &message{
+		Cmd:   eventHeartbeat,
+		Model: gatewayModel,
+		Sid:   s.cfg.SID,
+		Token: token,
+		Data:  string(data),
+	})
+}
+
+func (s *Simulator) sendEvent(cmd string, dev Device, data map[string]interface{}) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal '%v' data: %w", dev.SID, err)
+	}
+
+	return s.send(s.eventsConn, nil, &message{
+		Cmd:   cmd,
+		Model: dev.Model,
+		Sid:   dev.SID,
+		Data:  string(encoded),
+	})
+}
+
+func (s *Simulator) send(conn *net.UDPConn, addr *net.UDPAddr, msg *message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	return s.sendBytes(conn, addr, data)
+}
+
+func (s *Simulator) sendBytes(conn *net.UDPConn, addr *net.UDPAddr, data []byte) error {
+	var err error
+	if addr == nil {
+		_, err = conn.Write(data)
+	} else {
+		_, err = conn.WriteToUDP(data, addr)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Simulator) reader() {
+	defer s.done.Done()
+
+	buf := make([]byte, 2048)
+	for {
+		size, addr, err := s.conn.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-s.stopped:
+				return
+			default:
+				log.Printf("Simulator failed to read UDP: %v", err)
+				continue
+			}
+		}
+
+		s.processRequest(buf[:size], addr)
+	}
+}
+
+func (s *Simulator) processRequest(data []byte, addr *net.UDPAddr) {
+	var msg message
+	err := json.Unmarshal(data, &msg)
+	if err != nil {
+		log.Printf("Simulator got malformed request '%s': %v", string(data), err)
+		return
+	}
+
+	req := Request{
+		Cmd:  msg.Cmd,
+		SID:  msg.Sid,
+		Data: msg.Data,
+	}
+
+	s.mutex.Lock()
+	hook := s.hook
+	s.mutex.Unlock()
+
+	if hook != nil {
+		if reply, handled := hook(req); handled {
+			if reply != nil {
+				err = s.sendBytes(s.conn, addr, reply)
+			}
+			s.logSendError(req, err)
+			return
+		}
+	}
+
+	reply, report := s.answer(req)
+	if reply != nil {
+		s.logSendError(req, s.send(s.conn, addr, reply))
+	}
+
+	if report != nil {
+		s.logEventError(s.send(s.eventsConn, nil, report))
+	}
+}
+
+func (s *Simulator) logSendError(req Request, err error) {
+	if err != nil {
+		log.Printf("Simulator failed to answer '%v': %v", req.Cmd, err)
+	}
+}
+
+// answer returns the reply to the request and the report event to emit if the request changed a device.
+func (s *Simulator) answer(req Request) (*message, *message) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	switch req.Cmd {
+	case requestWhois:
+		return &message{
+			Cmd:          answerIam,
+			Model:        gatewayModel,
+			Sid:          s.cfg.SID,
+			IP:           s.Addr().IP.String(),
+			Port:         strconv.Itoa(s.Addr().Port),
+			ProtoVersion: protoVersion,
+		}, nil
+	case requestGetChildDevices:
+		list, _ := json.Marshal(s.order)
+		return &message{
+			Cmd:   req.Cmd + cmdAckSuffix,
+			Sid:   s.cfg.SID,
+			Token: s.token,
+			Data:  string(list),
+		}, nil
+	case requestReadDevice:
+		dev := s.findDevice(req.SID)
+		if dev == nil {
+			return errorAnswer(req, "No device"), nil
+		}
+
+		data, _ := json.Marshal(dev.Data)
+		return &message{
+			Cmd:   req.Cmd + cmdAckSuffix,
+			Model: dev.Model,
+			Sid:   dev.SID,
+			Data:  string(data),
+		}, nil
+	case requestWriteDevice:
+		return s.write(req)
+	default:
+		log.Printf("Simulator got unsupported request '%v'", req.Cmd)
+		return errorAnswer(req, "Unknown cmd"), nil
+	}
+}
+
+func (s *Simulator) write(req Request) (*message, *message) {
+	dev := s.findDevice(req.SID)
+	if dev == nil {
+		return errorAnswer(req, "No device"), nil
+	}
+
+	changes := make(map[string]interface{})
+	err := json.Unmarshal([]byte(req.Data), &changes)
+	if err != nil {
+		return errorAnswer(req, "Invalid data"), nil
+	}
+
+	key, _ := changes["key"].(string)
+	delete(changes, "key")
+	if s.cfg.Password != "" && key != s.expectedKey() {
+		return errorAnswer(req, "Invalid key"), nil
+	}
+
+	if dev.Data == nil {
+		dev.Data = make(map[string]interface{}, len(changes))
+	}
+
+	for field, value := range changes {
+		dev.Data[field] = value
+	}
+
+	data, _ := json.Marshal(dev.Data)
+	reportData, _ := json.Marshal(changes)
+
+	return &message{
+		Cmd:   req.Cmd + cmdAckSuffix,
+		Model: dev.Model,
+		Sid:   dev.SID,
+		Data:  string(data),
+	}, &message{
+		Cmd:   eventReport,
+		Model: dev.Model,
+		Sid:   dev.SID,
+		Data:  string(reportData),
+	}
+}
+
+// expectedKey returns the current token encrypted with the password, the way clients sign write requests.
+func (s *Simulator) expectedKey() string {
+	block, err := aes.NewCipher([]byte(s.cfg.Password))
+	if err != nil {
+		return ""
+	}
+
+	cipherText := make([]byte, len(s.token))
+	cipher.NewCBCEncrypter(block, initVector).CryptBlocks(cipherText, []byte(s.token))
+
+	return fmt.Sprintf("%X", cipherText)
+}
+
+func (s *Simulator) scheduler() {
+	defer s.done.Done()
+
+	heartBeatTicker := time.NewTicker(s.cfg.HeartBeatInterval)
+	deviceHeartBeatTicker := time.NewTicker(s.cfg.DeviceHeartBeatInterval)
+	reportTicker := time.NewTicker(s.cfg.ReportInterval)
+	defer heartBeatTicker.Stop()
+	defer deviceHeartBeatTicker.Stop()
+	defer reportTicker.Stop()
+
+	for {
+		select {
+		case <-s.stopped:
+			return
+		case <-heartBeatTicker.C:
+			s.logEventError(s.gatewayHeartBeat())
+		case <-deviceHeartBeatTicker.C:
+			for _, sid := range s.deviceSIDs() {
+				s.logEventError(s.HeartBeat(sid))
+			}
+		case <-reportTicker.C:
+			for _, sid := range s.deviceSIDs() {
+				s.logEventError(s.Report(sid, s.scheduledReport(sid)))
+			}
+		}
+	}
+}
+
+func (s *Simulator) logEventError(err error) {
+	if err != nil {
+		log.Printf("Simulator failed to send event: %v", err)
+	}
+}
+
+func (s *Simulator) deviceSIDs() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]string(nil), s.order...)
+}
+
+// scheduledReport returns the changed device fields, all drifting fields when drift is enabled.
+func (s *Simulator) scheduledReport(sid string) map[string]interface{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	report := make(map[string]interface{})
+	dev := s.devices[sid]
+	if dev == nil {
+		return report
+	}
+
+	for key, value := range dev.Data {
+		step, fnd := driftingFields[key]
+		if !fnd {
+			continue
+		}
+
+		strValue, ok := value.(string)
+		if !ok {
+			continue
+		}
+
+		numValue, err := strconv.Atoi(strValue)
+		if err != nil {
+			continue
+		}
+
+		if s.cfg.Drift {
+			numValue += rand.Intn(2*step+1) - step
+		}
+
+		report[key] = strconv.Itoa(numValue)
+	}
+
+	return report
+}
+
+func errorAnswer(req Request, errorText string) *message {
+	data, _ := json.Marshal(map[string]string{"error": errorText})
+	return &message{
+		Cmd:  req.Cmd + cmdAckSuffix,
+		Sid:  req.SID,
+		Data: string(data),
+	}
+}
+
+func copyDevice(dev *Device) Device {
+	data := make(map[string]interface{}, len(dev.Data))
+	for key, value := range dev.Data {
+		data[key] = value
+	}
+
+	return Device{
+		SID:   dev.SID,
+		Model: dev.Model,
+		Data:  data,
+	}
+}
+
+func newToken() string {
+	var token strings.Builder
+	for i := 0; i < tokenLength; i++ {
+		token.WriteByte(tokenLetters[rand.Intn(len(tokenLetters))])
+	}
+
+	return token.String()
+}
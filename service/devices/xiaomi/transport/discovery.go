@@ -0,0 +1,167 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	discoveryPort    = 4321
+	discoveryTimeout = 3 * time.Second
+
+	cmdWhois = "whois"
+	cmdIam   = "iam"
+)
+
+// discoveryAddress is where the "whois" request is sent, tests point it at a simulator.
+var discoveryAddress = &net.UDPAddr{IP: net.ParseIP(multicastAddress), Port: discoveryPort}
+
+type GatewayInfo struct {
+	SID          string
+	Model        string
+	Address      net.IP
+	Port         int
+	ProtoVersion string
+}
+
+type iamMessage struct {
+	Cmd          string `json:"cmd"`
+	Sid          string `json:"sid"`
+	Model        string `json:"model"`
+	IP           string `json:"ip"`
+	Port         string `json:"port"`
+	ProtoVersion string `json:"proto_version"`
+}
+
+// Discover sends the "whois" multicast request and collects "iam" answers until the context is done.
+func Discover(ctx context.Context) ([]GatewayInfo, error) {
+	gateways := make([]GatewayInfo, 0)
+	err := discover(ctx, func(info GatewayInfo) bool {
+		for _, known := range gateways {
+			if known.SID == info.SID {
+				return false
+			}
+		}
+
+		gateways = append(gateways, info)
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return gateways, nil
+}
+
+// Resolve looks up the address of the gateway with the given SID on the local network.
+func Resolve(ctx context.Context, sid string) (*GatewayInfo, error) {
+	var found *GatewayInfo
+	err := discover(ctx, func(info GatewayInfo) bool {
+		if info.SID != sid {
+			return false
+		}
+
+		found = &info
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if found == nil {
+		return nil, fmt.Errorf("gateway with sid '%v' not found", sid)
+	}
+
+	return found, nil
+}
+
+func discover(ctx context.Context, onFound func(info GatewayInfo) (stop bool)) error {
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return fmt.Errorf("failed to listen UDP: %w", err)
+	}
+
+	defer func() { _ = conn.Close() }()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(discoveryTimeout)
+	}
+
+	err = conn.SetReadDeadline(deadline)
+	if err != nil {
+		return fmt.Errorf("failed to set read deadline: %w", err)
+	}
+
+	request, _ := json.Marshal(&message{Cmd: cmdWhois})
+	_, err = conn.WriteToUDP(request, discoveryAddress)
+	if err != nil {
+		return fmt.Errorf("failed to send '%v' request: %w", cmdWhois, err)
+	}
+
+	buf := make([]byte, 2048)
+	for {
+		size, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				return nil
+			}
+
+			return fmt.Errorf("failed to read '%v' response: %w", cmdIam, err)
+		}
+
+		info, err := parseIam(buf[:size])
+		if err != nil {
+			log.Printf("Skipping discovery response: %v", err)
+			continue
+		}
+
+		if onFound(*info) {
+			return nil
+		}
+
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+func parseIam(data []byte) (*GatewayInfo, error) {
+	var msg iamMessage
+	err := json.Unmarshal(data, &msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse '%s': %w", string(data), err)
+	}
+
+	if msg.Cmd != cmdIam {
+		return nil, fmt.Errorf("unexpected command '%v'", msg.Cmd)
+	}
+
+	address := net.ParseIP(msg.IP)
+	if address == nil {
+		return nil, fmt.Errorf("failed to decode gateway IP-address: %v", msg.IP)
+	}
+
+	gatewayPort := port
+	if msg.Port != "" {
+		gatewayPort, err = strconv.Atoi(msg.Port)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode gateway port '%v': %w", msg.Port, err)
+		}
+	}
+
+	return &GatewayInfo{
+		SID:          msg.Sid,
+		Model:        msg.Model,
+		Address:      address,
+		Port:         gatewayPort,
+		ProtoVersion: msg.ProtoVersion,
+	}, nil
+}
@@ -0,0 +1,116 @@
+package transport
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cherserver/infocenter/service/devices/xiaomi/internal/simtest"
+	"github.com/cherserver/infocenter/service/devices/xiaomi/simulator"
+)
+
+func TestParseIam(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		info *GatewayInfo
+		err  string
+	}{
+		{
+			name: "valid",
+			data: `{"cmd":"iam","port":"9898","sid":"7811dcb25bfe","model":"gateway","proto_version":"1.1.2","ip":"192.168.31.21"}`,
+			info: &GatewayInfo{
+				SID:          "7811dcb25bfe",
+				Model:        "gateway",
+				Address:      net.ParseIP("192.168.31.21"),
+				Port:         9898,
+				ProtoVersion: "1.1.2",
+			},
+		},
+		{
+			name: "default port",
+			data: `{"cmd":"iam","sid":"7811dcb25bfe","ip":"192.168.31.21"}`,
+			info: &GatewayInfo{SID: "7811dcb25bfe", Address: net.ParseIP("192.168.31.21"), Port: port},
+		},
+		{
+			name: "malformed json",
+			data: `{"cmd":"iam",`,
+			err:  `failed to parse '{"cmd":"iam",'`,
+		},
+		{
+			name: "wrong cmd",
+			data: `{"cmd":"heartbeat","sid":"7811dcb25bfe","ip":"192.168.31.21"}`,
+			err:  "unexpected command 'heartbeat'",
+		},
+		{
+			name: "missing ip",
+			data: `{"cmd":"iam","sid":"7811dcb25bfe"}`,
+			err:  "failed to decode gateway IP-address",
+		},
+		{
+			name: "non-numeric port",
+			data: `{"cmd":"iam","port":"http","sid":"7811dcb25bfe","ip":"192.168.31.21"}`,
+			err:  "failed to decode gateway port 'http'",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			info, err := parseIam([]byte(test.data))
+			if test.err != "" {
+				require.ErrorContains(t, err, test.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, test.info, info)
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	sim := simtest.Start(t, simulator.Config{})
+
+	defaultAddress := discoveryAddress
+	discoveryAddress = sim.Addr()
+	t.Cleanup(func() { discoveryAddress = defaultAddress })
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), time.Second)
+	defer cancelFunc()
+
+	info, err := Resolve(ctx, sim.SID())
+	require.NoError(t, err)
+	require.Equal(t, sim.SID(), info.SID)
+	require.Equal(t, "gateway", info.Model)
+	require.True(t, info.Address.Equal(sim.Addr().IP))
+	require.Equal(t, sim.Addr().Port, info.Port)
+
+	gateways, err := Discover(ctx)
+	require.NoError(t, err)
+	require.Len(t, gateways, 1)
+	require.Equal(t, sim.SID(), gateways[0].SID)
+
+	ctx, cancelFunc = context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancelFunc()
+
+	_, err = Resolve(ctx, "7811dcb20000")
+	require.ErrorContains(t, err, "gateway with sid '7811dcb20000' not found")
+
+	trans, err := New(Config{
+		Name:           "test",
+		SID:            sim.SID(),
+		Token:          simtest.Token,
+		EventsAddress:  sim.EventsAddr().String(),
+		RequestTimeout: 200 * time.Millisecond,
+		Retry:          RetryPolicy{Attempts: 1},
+	})
+	require.NoError(t, err)
+	require.NoError(t, trans.Start())
+	t.Cleanup(func() { _ = trans.Stop() })
+
+	require.NoError(t, trans.Connect())
+	require.Equal(t, sim.SID(), trans.GatewaySID())
+}
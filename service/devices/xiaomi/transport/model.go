@@ -16,8 +16,18 @@ type deviceCommand struct {
 	Params []interface{} `json:"params,omitempty"`
 }
 
+type awaitingRequest struct {
+	id      messageID
+	sid     string
+	outChan chan response
+}
+
 type response struct {
 	msg  *message
 	data []byte
 	err  error
 }
+
+type gatewayHeartBeatData struct {
+	IP string `json:"ip"`
+}
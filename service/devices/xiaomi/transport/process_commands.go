@@ -2,87 +2,133 @@ package transport
 
 import (
 	"context"
+	"crypto/aes"
 	"crypto/cipher"
 	"encoding/json"
 	"fmt"
 	"log"
+	"time"
 )
 
 func (t *Transport) processIncomingCmd(msg *message) {
-	outChan := t.getAwaitingByName(msg.Cmd)
-	if outChan == nil {
+	awaiting := t.takeAwaiting(msg.Cmd, msg.Sid)
+	if awaiting == nil {
 		log.Printf("Unexpected response: %+v", msg)
 		return
 	}
 
-	outChan <- response{
+	awaiting.outChan <- response{
 		msg:  msg,
 		data: []byte(msg.Data),
 		err:  nil,
 	}
 }
 
-func (t *Transport) queueAwaitingByName(cmd string) (chan response, error) {
-	t.awaitingByNameMutex.Lock()
-	defer t.awaitingByNameMutex.Unlock()
+// queueAwaiting registers a request awaiting response, requests are queued per command name.
+func (t *Transport) queueAwaiting(cmd string, sid string) *awaitingRequest {
+	t.awaitingMutex.Lock()
+	defer t.awaitingMutex.Unlock()
 
-	if _, fnd := t.awaitingByName[cmd]; fnd {
-		return nil, fmt.Errorf("command '%v' is already awaiting response", cmd)
+	t.lastMessageID++
+	awaiting := &awaitingRequest{
+		id:      t.lastMessageID,
+		sid:     sid,
+		outChan: make(chan response, 1),
 	}
 
-	outChan := make(chan response, 0)
-	t.awaitingByName[cmd] = outChan
-	return outChan, nil
+	t.awaiting[cmd] = append(t.awaiting[cmd], awaiting)
+	return awaiting
 }
 
-func (t *Transport) getAwaitingByName(cmd string) chan response {
-	t.awaitingByNameMutex.Lock()
-	defer t.awaitingByNameMutex.Unlock()
+// takeAwaiting removes and returns the request the response belongs to: the oldest one for the same SID,
+// otherwise the oldest one sent without SID. Responses for other SIDs, e.g. late ones, belong to no request.
+func (t *Transport) takeAwaiting(cmd string, sid string) *awaitingRequest {
+	t.awaitingMutex.Lock()
+	defer t.awaitingMutex.Unlock()
+
+	queue := t.awaiting[cmd]
+	found := -1
+	for idx, awaiting := range queue {
+		if awaiting.sid == sid {
+			found = idx
+			break
+		}
 
-	return t.awaitingByName[cmd]
-}
+		if awaiting.sid == "" && found < 0 {
+			found = idx
+		}
+	}
 
-func (t *Transport) dropAwaitingByName(cmd string) chan response {
-	t.awaitingByNameMutex.Lock()
-	defer t.awaitingByNameMutex.Unlock()
+	if found < 0 {
+		return nil
+	}
 
-	outChan, _ := t.awaitingByName[cmd]
-	delete(t.awaitingByName, cmd)
+	awaiting := queue[found]
+	t.removeAwaiting(cmd, found)
 
-	return outChan
+	return awaiting
 }
 
-func (t *Transport) request(sid string, cmd string, data map[string]interface{}) <-chan response {
-	outChan, err := t.queueAwaitingByName(cmd)
-	if err != nil {
-		outErrChan := make(chan response, 0)
+func (t *Transport) dropAwaiting(cmd string, id messageID) {
+	t.awaitingMutex.Lock()
+	defer t.awaitingMutex.Unlock()
 
-		go func() {
-			outErrChan <- response{
-				data: nil,
-				err:  err,
+	for idx, awaiting := range t.awaiting[cmd] {
+		if awaiting.id == id {
+			t.removeAwaiting(cmd, idx)
+			return
+		}
+	}
+}
+
+func (t *Transport) removeAwaiting(cmd string, idx int) {
+	queue := t.awaiting[cmd]
+	if len(queue) == 1 {
+		delete(t.awaiting, cmd)
+		return
+	}
+
+	t.awaiting[cmd] = append(queue[:idx:idx], queue[idx+1:]...)
+}
+
+// requestWithRetry repeats the request according to the retry policy until it succeeds.
+func (t *Transport) requestWithRetry(sid string, cmd string, data map[string]interface{}) response {
+	var resp response
+	for attempt := 0; attempt < t.retryPolicy.Attempts; attempt++ {
+		if attempt > 0 {
+			delay := t.retryPolicy.Delay(attempt - 1)
+			log.Printf("Request '%v' to '%v' failed: %v, retrying in %v", cmd, sid, resp.err, delay)
+
+			select {
+			case <-t.stopped:
+				return resp
+			case <-time.After(delay):
 			}
-			close(outErrChan)
-		}()
+		}
 
-		return outErrChan
+		resp = <-t.request(sid, cmd, data)
+		if resp.err == nil {
+			return resp
+		}
 	}
 
+	return resp
+}
+
+func (t *Transport) request(sid string, cmd string, data map[string]interface{}) <-chan response {
 	responseChan := make(chan response, 0)
 
-	mode := cipher.NewCBCEncrypter(t.token, initVector)
-	cipherText := make([]byte, len(t.gatewayToken))
-	mode.CryptBlocks(cipherText, []byte(t.gatewayToken))
 	cmdObj := &message{
 		Sid: sid,
 		Cmd: cmd,
 	}
 
-	if data == nil {
-		data = make(map[string]interface{}, 1)
+	signedData := make(map[string]interface{}, len(data)+1)
+	for key, value := range data {
+		signedData[key] = value
 	}
-	data["key"] = fmt.Sprintf("%X", cipherText)
-	bytes, _ := json.Marshal(data)
+	signedData["key"] = t.key()
+	bytes, _ := json.Marshal(signedData)
 	cmdObj.Data = string(bytes)
 
 	cmdJson, err := json.Marshal(cmdObj)
@@ -97,8 +143,11 @@ func (t *Transport) request(sid string, cmd string, data map[string]interface{})
 		return responseChan
 	}
 
+	awaiting := t.queueAwaiting(cmd, sid)
+
 	err = t.sendMessage(cmdJson)
 	if err != nil {
+		t.dropAwaiting(cmd, awaiting.id)
 		log.Printf("Failed to send CMD: %s", err.Error())
 		go func() {
 			responseChan <- response{
@@ -110,23 +159,37 @@ func (t *Transport) request(sid string, cmd string, data map[string]interface{})
 		return responseChan
 	}
 
-	go t.awaitResponseByName(cmd, outChan, responseChan)
+	go t.awaitResponse(cmd, awaiting, responseChan)
 
 	return responseChan
 }
 
-func (t *Transport) awaitResponseByName(cmd string, outChan chan response, responseChan chan response) {
-	requestCtx, cancelFunc := context.WithTimeout(t.ctx, requestTimeout)
+// key signs requests: the current gateway token encrypted with the gateway password.
+func (t *Transport) key() string {
+	token := t.gatewayToken.Load()
+	if token == nil || len(*token)%aes.BlockSize != 0 {
+		return ""
+	}
+
+	mode := cipher.NewCBCEncrypter(t.token, initVector)
+	cipherText := make([]byte, len(*token))
+	mode.CryptBlocks(cipherText, []byte(*token))
+
+	return fmt.Sprintf("%X", cipherText)
+}
+
+func (t *Transport) awaitResponse(cmd string, awaiting *awaitingRequest, responseChan chan response) {
+	requestCtx, cancelFunc := context.WithTimeout(t.ctx, t.requestTimeout)
 	defer cancelFunc()
 	select {
 	case <-requestCtx.Done():
-		t.dropAwaitingByName(cmd)
+		t.dropAwaiting(cmd, awaiting.id)
+		t.onRequestTimeout()
 		responseChan <- response{
 			data: nil,
 			err:  fmt.Errorf("request timed out"),
 		}
-	case responseData := <-outChan:
-		t.dropAwaitingByName(cmd)
+	case responseData := <-awaiting.outChan:
 		responseChan <- responseData
 	}
 
@@ -134,8 +197,13 @@ func (t *Transport) awaitResponseByName(cmd string, outChan chan response, respo
 }
 
 func (t *Transport) sendMessage(msg []byte) error {
+	address := t.address.Load()
+	if address == nil {
+		return fmt.Errorf("gateway address is not resolved")
+	}
+
 	log.Printf("Sending msg %s", string(msg))
-	_, err := t.conn.Write(msg)
+	_, err := t.conn.WriteToUDP(msg, address)
 	if err != nil {
 		log.Printf("Error writing to UDP: %s", err.Error())
 		return err
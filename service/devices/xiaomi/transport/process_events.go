@@ -4,6 +4,9 @@ import "log"
 
 type EventConsumeFunc func(data string)
 
+// UnknownDeviceConsumeFunc is called for events of devices without registered consumers.
+type UnknownDeviceConsumeFunc func(sid string, model string)
+
 func (t *Transport) RegisterHeartBeatConsumer(sid string, consumeFunc EventConsumeFunc) {
 	t.heartBeatConsumersMutex.Lock()
 	defer t.heartBeatConsumersMutex.Unlock()
@@ -18,15 +21,48 @@ func (t *Transport) RegisterReportConsumer(sid string, consumeFunc EventConsumeF
 	t.reportConsumers[sid] = consumeFunc
 }
 
+func (t *Transport) RegisterUnknownDeviceConsumer(consumeFunc UnknownDeviceConsumeFunc) {
+	t.unknownDeviceConsumerMutex.Lock()
+	defer t.unknownDeviceConsumerMutex.Unlock()
+
+	t.unknownDeviceConsumer = consumeFunc
+}
+
+// UnregisterConsumers removes heartbeat and report consumers of the device.
+func (t *Transport) UnregisterConsumers(sid string) {
+	func() {
+		t.heartBeatConsumersMutex.Lock()
+		defer t.heartBeatConsumersMutex.Unlock()
+
+		delete(t.heartBeatConsumers, sid)
+	}()
+
+	func() {
+		t.reportConsumersMutex.Lock()
+		defer t.reportConsumersMutex.Unlock()
+
+		delete(t.reportConsumers, sid)
+	}()
+}
+
 func (t *Transport) processIncomingEvent(msg *message) {
+	consumed := false
+
 	switch msg.Cmd {
 	case eventHeartbeat:
+		if msg.Sid == t.GatewaySID() {
+			t.storeGatewayToken(msg.Token)
+			go t.onHeartBeat(msg.Data)
+			consumed = true
+		}
+
 		func() {
 			t.heartBeatConsumersMutex.Lock()
 			defer t.heartBeatConsumersMutex.Unlock()
 
 			if consumerFunc, fnd := t.heartBeatConsumers[msg.Sid]; fnd {
 				go consumerFunc(msg.Data)
+				consumed = true
 			}
 		}()
 	case eventReport:
@@ -36,9 +72,24 @@ func (t *Transport) processIncomingEvent(msg *message) {
 
 			if consumerFunc, fnd := t.reportConsumers[msg.Sid]; fnd {
 				go consumerFunc(msg.Data)
+				consumed = true
 			}
 		}()
 	default:
 		log.Printf("Unknown event: %+v", msg)
+		return
+	}
+
+	if !consumed {
+		t.onUnknownDevice(msg)
+	}
+}
+
+func (t *Transport) onUnknownDevice(msg *message) {
+	t.unknownDeviceConsumerMutex.Lock()
+	defer t.unknownDeviceConsumerMutex.Unlock()
+
+	if t.unknownDeviceConsumer != nil && msg.Sid != "" {
+		go t.unknownDeviceConsumer(msg.Sid, msg.Model)
 	}
 }
@@ -6,7 +6,7 @@ import (
 )
 
 func (t *Transport) RequestGetChildDevicesIDs() ([]string, error) {
-	resp := <-t.request(t.gatewaySID, requestGetChildDevices, nil)
+	resp := t.requestWithRetry(t.GatewaySID(), requestGetChildDevices, nil)
 	if resp.err != nil {
 		return nil, fmt.Errorf("failed to get devices list: %w", resp.err)
 	}
@@ -10,9 +10,9 @@ type ResponseReadDevice struct {
 }
 
 func (t *Transport) RequestReadDevice(sid string) (*ResponseReadDevice, error) {
-	resp := <-t.request(sid, requestReadDevice, nil)
+	resp := t.requestWithRetry(sid, requestReadDevice, nil)
 	if resp.err != nil {
-		return nil, fmt.Errorf("failed to get devices list: %w", resp.err)
+		return nil, fmt.Errorf("failed to read device: %w", resp.err)
 	}
 
 	return &ResponseReadDevice{
@@ -0,0 +1,33 @@
+package transport
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type ResponseWriteDevice struct {
+	Model string
+	Data  string
+}
+
+type writeErrorData struct {
+	Error string `json:"error"`
+}
+
+// RequestWriteDevice changes the device state, the request is signed with the current gateway token.
+func (t *Transport) RequestWriteDevice(sid string, data map[string]interface{}) (*ResponseWriteDevice, error) {
+	resp := t.requestWithRetry(sid, requestWriteDevice, data)
+	if resp.err != nil {
+		return nil, fmt.Errorf("failed to write device: %w", resp.err)
+	}
+
+	var errData writeErrorData
+	if json.Unmarshal(resp.data, &errData) == nil && errData.Error != "" {
+		return nil, fmt.Errorf("gateway refused to write device '%v': %v", sid, errData.Error)
+	}
+
+	return &ResponseWriteDevice{
+		Model: resp.msg.Model,
+		Data:  resp.msg.Data,
+	}, nil
+}
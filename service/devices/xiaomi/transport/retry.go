@@ -0,0 +1,66 @@
+package transport
+
+import (
+	"math/rand"
+	"time"
+)
+
+const (
+	defaultRetryAttempts   = 3
+	defaultRetryBackoff    = 500 * time.Millisecond
+	defaultRetryMaxBackoff = 5 * time.Second
+	defaultRetryJitter     = 0.2
+)
+
+// RetryPolicy describes how failed requests are repeated: the delay before the next attempt starts with Backoff,
+// doubles after every attempt up to MaxBackoff and is randomly changed by up to Jitter part of it.
+type RetryPolicy struct {
+	Attempts   int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	Jitter     float64 // zero disables the jitter, so it is never taken from DefaultRetryPolicy
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		Attempts:   defaultRetryAttempts,
+		Backoff:    defaultRetryBackoff,
+		MaxBackoff: defaultRetryMaxBackoff,
+		Jitter:     defaultRetryJitter,
+	}
+}
+
+// Delay returns the pause before the attempt following the given one, attempts are counted from zero.
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	delay := p.Backoff
+	for i := 0; i < attempt && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+
+	if p.Jitter > 0 {
+		delay += time.Duration((rand.Float64()*2 - 1) * p.Jitter * float64(delay))
+	}
+
+	return delay
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	defaults := DefaultRetryPolicy()
+	if p.Attempts == 0 {
+		p.Attempts = defaults.Attempts
+	}
+
+	if p.Backoff == 0 {
+		p.Backoff = defaults.Backoff
+	}
+
+	if p.MaxBackoff == 0 {
+		p.MaxBackoff = defaults.MaxBackoff
+	}
+
+	return p
+}
@@ -8,8 +8,10 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -23,6 +25,7 @@ const (
 
 	requestGetChildDevices = "get_id_list"
 	requestReadDevice      = "read"
+	requestWriteDevice     = "write"
 
 	eventHeartbeat = "heartbeat"
 	eventReport    = "report"
@@ -32,45 +35,103 @@ var (
 	initVector = []byte{0x17, 0x99, 0x6d, 0x09, 0x3d, 0x28, 0xdd, 0xb3, 0xba, 0x69, 0x5a, 0x2e, 0x6f, 0x58, 0x56, 0x2e}
 )
 
-func New(addr string, token string) (*Transport, error) {
-	tokenDecoded, err := aes.NewCipher([]byte(token))
+// Config describes how to reach a gateway: by a fixed IP-address or by its SID,
+// in which case the address is discovered on the local network.
+type Config struct {
+	Name    string
+	Address string
+	Port    int // gateway UDP port, 9898 when zero
+	SID     string
+	Token   string
+
+	// EventsAddress is where heartbeat and report events are listened for,
+	// "224.0.0.50:9898" multicast group when empty.
+	EventsAddress string
+
+	RequestTimeout time.Duration // 2 seconds when zero
+	Retry          RetryPolicy   // zero fields but Jitter are taken from DefaultRetryPolicy
+}
+
+func New(cfg Config) (*Transport, error) {
+	tokenDecoded, err := aes.NewCipher([]byte(cfg.Token))
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode gateway token: %w", err)
 	}
 
-	addressDecoded := net.ParseIP(addr)
-	if addressDecoded == nil {
-		return nil, fmt.Errorf("failed to decode gateway IP-address: %v", addr)
+	var addressDecoded net.IP
+	if cfg.Address != "" {
+		addressDecoded = net.ParseIP(cfg.Address)
+		if addressDecoded == nil {
+			return nil, fmt.Errorf("failed to decode gateway IP-address: %v", cfg.Address)
+		}
+	} else if cfg.SID == "" {
+		return nil, fmt.Errorf("either gateway IP-address or SID is required")
+	}
+
+	if cfg.Port == 0 {
+		cfg.Port = port
 	}
 
-	return &Transport{
-		address:        addressDecoded,
+	if cfg.EventsAddress == "" {
+		cfg.EventsAddress = net.JoinHostPort(multicastAddress, strconv.Itoa(port))
+	}
+
+	eventsAddress, err := net.ResolveUDPAddr("udp4", cfg.EventsAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode events address: %w", err)
+	}
+
+	if cfg.RequestTimeout == 0 {
+		cfg.RequestTimeout = requestTimeout
+	}
+
+	trans := &Transport{
+		name:           cfg.Name,
+		sid:            cfg.SID,
+		port:           cfg.Port,
+		eventsAddress:  eventsAddress,
+		requestTimeout: cfg.RequestTimeout,
+		retryPolicy:    cfg.Retry.withDefaults(),
 		token:          tokenDecoded,
 		conn:           nil,
-		awaiting:       make(map[messageID]chan response, 0),
-		awaitingByName: make(map[string]chan response, 0),
+		awaiting:       make(map[string][]*awaitingRequest, 0),
 		stopped:        make(chan struct{}),
 		ctx:            context.Background(),
 
 		heartBeatConsumers: make(map[string]EventConsumeFunc, 0),
 		reportConsumers:    make(map[string]EventConsumeFunc, 0),
-	}, nil
+	}
+
+	if addressDecoded != nil {
+		trans.address.Store(&net.UDPAddr{
+			IP:   addressDecoded,
+			Port: cfg.Port,
+		})
+	}
+
+	return trans, nil
 }
 
 type Transport struct {
-	address       net.IP
+	name          string
+	sid           string
+	port          int
+	address       atomic.Pointer[net.UDPAddr]
+	eventsAddress *net.UDPAddr
+	resolving     atomic.Bool
 	token         cipher.Block
 	conn          *net.UDPConn
 	multicastConn *net.UDPConn
 
-	gatewaySID   string
-	gatewayToken string
+	requestTimeout time.Duration
+	retryPolicy    RetryPolicy
 
-	awaiting      map[messageID]chan response
-	awaitingMutex sync.Mutex
+	gatewaySID   atomic.Pointer[string]
+	gatewayToken atomic.Pointer[string] // changes with every gateway heartbeat
 
-	awaitingByName      map[string]chan response
-	awaitingByNameMutex sync.Mutex
+	awaiting      map[string][]*awaitingRequest
+	awaitingMutex sync.Mutex
+	lastMessageID messageID
 
 	heartBeatConsumers      map[string]EventConsumeFunc
 	heartBeatConsumersMutex sync.Mutex
@@ -78,28 +139,27 @@ type Transport struct {
 	reportConsumers      map[string]EventConsumeFunc
 	reportConsumersMutex sync.Mutex
 
+	unknownDeviceConsumer      UnknownDeviceConsumeFunc
+	unknownDeviceConsumerMutex sync.Mutex
+
 	stopped chan struct{}
 
 	ctx context.Context
 }
 
+// Start opens the gateway sockets, the gateway itself is contacted by Connect.
 func (t *Transport) Start() error {
-	udpAddr := &net.UDPAddr{
-		IP:   t.address,
-		Port: port,
-	}
-
 	var err error
-	t.conn, err = net.DialUDP("udp4", nil, udpAddr)
+	t.conn, err = net.ListenUDP("udp4", nil)
 	if err != nil {
-		return fmt.Errorf("failed to dial gateway UDP: %w", err)
+		return fmt.Errorf("failed to listen gateway UDP: %w", err)
 	}
 
-	multicastAddr := &net.UDPAddr{
-		IP:   net.ParseIP(multicastAddress),
-		Port: port,
+	if t.eventsAddress.IP.IsMulticast() {
+		t.multicastConn, err = net.ListenMulticastUDP("udp4", nil, t.eventsAddress)
+	} else {
+		t.multicastConn, err = net.ListenUDP("udp4", t.eventsAddress)
 	}
-	t.multicastConn, err = net.ListenMulticastUDP("udp4", nil, multicastAddr)
 	if err != nil {
 		return fmt.Errorf("failed to dial multicast UDP: %w", err)
 	}
@@ -107,21 +167,41 @@ func (t *Transport) Start() error {
 	go t.reader()
 	go t.multicastReader()
 
-	resp := <-t.request("", requestGetChildDevices, nil)
+	return nil
+}
+
+// Connect resolves the gateway address when needed and gets the gateway SID and token.
+func (t *Transport) Connect() error {
+	if t.sid != "" {
+		err := t.resolve()
+		if err != nil {
+			return err
+		}
+	}
+
+	resp := t.requestWithRetry("", requestGetChildDevices, nil)
 	if resp.err != nil {
 		return fmt.Errorf("failed to call gateway devices list: %w", resp.err)
 	}
 
-	t.gatewaySID = resp.msg.Sid
-	t.gatewayToken = resp.msg.Token
+	if t.sid != "" && t.sid != resp.msg.Sid {
+		return fmt.Errorf("gateway at %v has sid '%v', expected '%v'", t.address.Load(), resp.msg.Sid, t.sid)
+	}
 
-	t.RegisterHeartBeatConsumer(t.gatewaySID, t.onHeartBeat)
+	gatewaySID := resp.msg.Sid
+	t.gatewaySID.Store(&gatewaySID)
+	t.storeGatewayToken(resp.msg.Token)
 
-	log.Printf("Transport successfully started (%v)", t.address.String())
+	log.Printf("Transport '%v' successfully connected (%v)", t.name, t.address.Load())
 
 	return nil
 }
 
+// Name returns the gateway name the transport was created for.
+func (t *Transport) Name() string {
+	return t.name
+}
+
 func (t *Transport) Stop() error {
 	close(t.stopped)
 
@@ -129,6 +209,10 @@ func (t *Transport) Stop() error {
 		_ = t.conn.Close()
 	}
 
+	if t.multicastConn != nil {
+		_ = t.multicastConn.Close()
+	}
+
 	return nil
 }
 
@@ -196,6 +280,79 @@ func (t *Transport) processIncomingMessage(msg []byte) {
 	}
 }
 
-func (t *Transport) onHeartBeat(string) {
-	log.Printf("Got gateway heartbeat")
+// GatewaySID returns the SID of the gateway itself, known once connected.
+func (t *Transport) GatewaySID() string {
+	ptr := t.gatewaySID.Load()
+	if ptr == nil {
+		return ""
+	}
+
+	return *ptr
+}
+
+func (t *Transport) storeGatewayToken(token string) {
+	if token != "" {
+		t.gatewayToken.Store(&token)
+	}
+}
+
+// onHeartBeat follows the gateway address change, the heartbeat is also passed to the gateway device consumer.
+func (t *Transport) onHeartBeat(data string) {
+	log.Printf("Got gateway '%v' heartbeat", t.name)
+
+	if t.sid == "" {
+		return
+	}
+
+	var heartBeat gatewayHeartBeatData
+	err := json.Unmarshal([]byte(data), &heartBeat)
+	if err != nil || heartBeat.IP == "" {
+		return
+	}
+
+	address := net.ParseIP(heartBeat.IP)
+	if address == nil || address.Equal(t.address.Load().IP) {
+		return
+	}
+
+	log.Printf("Gateway '%v' moved to %v", t.name, address)
+	t.address.Store(&net.UDPAddr{
+		IP:   address,
+		Port: t.port,
+	})
+}
+
+// resolve looks up the current gateway address by its SID.
+func (t *Transport) resolve() error {
+	ctx, cancelFunc := context.WithTimeout(t.ctx, discoveryTimeout)
+	defer cancelFunc()
+
+	info, err := Resolve(ctx, t.sid)
+	if err != nil {
+		return fmt.Errorf("failed to resolve gateway '%v': %w", t.name, err)
+	}
+
+	log.Printf("Gateway '%v' resolved to %v:%v", t.name, info.Address, info.Port)
+	t.address.Store(&net.UDPAddr{
+		IP:   info.Address,
+		Port: info.Port,
+	})
+
+	return nil
+}
+
+// onRequestTimeout re-resolves the gateway in background, as DHCP could have moved it.
+func (t *Transport) onRequestTimeout() {
+	if t.sid == "" || !t.resolving.CompareAndSwap(false, true) {
+		return
+	}
+
+	go func() {
+		defer t.resolving.Store(false)
+
+		err := t.resolve()
+		if err != nil {
+			log.Printf("Failed to re-resolve gateway: %v", err)
+		}
+	}()
 }
@@ -0,0 +1,208 @@
+package transport
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cherserver/infocenter/service/devices/xiaomi/internal/simtest"
+	"github.com/cherserver/infocenter/service/devices/xiaomi/simulator"
+)
+
+const testSensorSID = "158d0001fd4989"
+
+// startSimulator starts a simulator with a climate sensor and connects a transport to it.
+func startSimulator(t *testing.T) (*simulator.Simulator, *Transport) {
+	sim := simtest.Start(t, simulator.Config{
+		Devices: []simulator.Device{
+			{
+				SID:   testSensorSID,
+				Model: "sensor_ht",
+				Data:  map[string]interface{}{"voltage": 3005, "temperature": "2150", "humidity": "4520"},
+			},
+		},
+	})
+
+	trans, err := New(Config{
+		Name:           "test",
+		Address:        "127.0.0.1",
+		Port:           sim.Addr().Port,
+		Token:          simtest.Token,
+		EventsAddress:  sim.EventsAddr().String(),
+		RequestTimeout: 200 * time.Millisecond,
+		Retry:          RetryPolicy{Attempts: 1},
+	})
+	require.NoError(t, err)
+	require.NoError(t, trans.Start())
+	t.Cleanup(func() { _ = trans.Stop() })
+	require.NoError(t, trans.Connect())
+
+	return sim, trans
+}
+
+func TestRequestReadDevice(t *testing.T) {
+	sim, trans := startSimulator(t)
+
+	devList, err := trans.RequestGetChildDevicesIDs()
+	require.NoError(t, err)
+	require.Equal(t, []string{testSensorSID}, devList)
+	require.Equal(t, sim.SID(), trans.GatewaySID())
+
+	info, err := trans.RequestReadDevice(testSensorSID)
+	require.NoError(t, err)
+	require.Equal(t, "sensor_ht", info.Model)
+	require.JSONEq(t, `{"voltage":3005,"temperature":"2150","humidity":"4520"}`, info.Data)
+}
+
+func TestRequestTimeout(t *testing.T) {
+	sim, trans := startSimulator(t)
+
+	sim.SetHook(func(req simulator.Request) ([]byte, bool) {
+		return nil, true
+	})
+
+	_, err := trans.RequestReadDevice(testSensorSID)
+	require.ErrorContains(t, err, "request timed out")
+}
+
+func TestRequestRetry(t *testing.T) {
+	sim, trans := startSimulator(t)
+	trans.retryPolicy = RetryPolicy{Attempts: 3, Backoff: 10 * time.Millisecond}
+
+	var dropped atomic.Int32
+	sim.SetHook(func(req simulator.Request) ([]byte, bool) {
+		return nil, dropped.Add(1) <= 2
+	})
+
+	info, err := trans.RequestReadDevice(testSensorSID)
+	require.NoError(t, err)
+	require.Equal(t, "sensor_ht", info.Model)
+	require.Equal(t, int32(3), dropped.Load())
+}
+
+func TestMalformedResponse(t *testing.T) {
+	sim, trans := startSimulator(t)
+
+	sim.SetHook(func(req simulator.Request) ([]byte, bool) {
+		return []byte(`{"cmd":"read_ack",`), true
+	})
+
+	_, err := trans.RequestReadDevice(testSensorSID)
+	require.ErrorContains(t, err, "request timed out")
+}
+
+func TestReportEvent(t *testing.T) {
+	sim, trans := startSimulator(t)
+
+	reports := make(chan string, 1)
+	trans.RegisterReportConsumer(testSensorSID, func(data string) {
+		reports <- data
+	})
+
+	require.NoError(t, sim.SendRaw([]byte("not a json")))
+	require.NoError(t, sim.Report(testSensorSID, map[string]interface{}{"temperature": "2210"}))
+
+	select {
+	case data := <-reports:
+		require.JSONEq(t, `{"temperature":"2210"}`, data)
+	case <-time.After(time.Second):
+		require.Fail(t, "report was not delivered")
+	}
+}
+
+func TestConcurrentReads(t *testing.T) {
+	sim, trans := startSimulator(t)
+
+	const readsCount = 10
+	for i := 0; i < readsCount; i++ {
+		sim.AddDevice(simulator.Device{
+			SID:   fmt.Sprintf("158d00010000%02d", i),
+			Model: "sensor_ht",
+			Data:  map[string]interface{}{"temperature": fmt.Sprintf("%d", 2000+i)},
+		})
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, readsCount)
+	infos := make([]*ResponseReadDevice, readsCount)
+	for i := 0; i < readsCount; i++ {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			infos[idx], errs[idx] = trans.RequestReadDevice(fmt.Sprintf("158d00010000%02d", idx))
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < readsCount; i++ {
+		require.NoError(t, errs[i])
+		require.JSONEq(t, fmt.Sprintf(`{"temperature":"%d"}`, 2000+i), infos[i].Data)
+	}
+}
+
+func TestRequestWriteDevice(t *testing.T) {
+	sim, trans := startSimulator(t)
+
+	const plugSID = "158d0002ab1234"
+	sim.AddDevice(simulator.Device{
+		SID:   plugSID,
+		Model: "plug",
+		Data:  map[string]interface{}{"status": "off"},
+	})
+
+	_, err := trans.RequestGetChildDevicesIDs()
+	require.NoError(t, err)
+
+	resp, err := trans.RequestWriteDevice(plugSID, map[string]interface{}{"status": "on"})
+	require.NoError(t, err)
+	require.Equal(t, "plug", resp.Model)
+	require.JSONEq(t, `{"status":"on"}`, resp.Data)
+
+	_, err = trans.RequestWriteDevice("158d0000000000", map[string]interface{}{"status": "on"})
+	require.ErrorContains(t, err, "No device")
+}
+
+func TestLateResponseForOtherDevice(t *testing.T) {
+	sim, trans := startSimulator(t)
+
+	const otherSID = "158d0001fd5000"
+	sim.AddDevice(simulator.Device{
+		SID:   otherSID,
+		Model: "sensor_ht",
+		Data:  map[string]interface{}{"temperature": "1800"},
+	})
+
+	// the gateway answers the request for the other device with a late response for the sensor
+	sim.SetHook(func(req simulator.Request) ([]byte, bool) {
+		if req.SID != otherSID {
+			return nil, false
+		}
+
+		return []byte(fmt.Sprintf(`{"cmd":"read_ack","model":"sensor_ht","sid":"%v","data":"{\"temperature\":\"2150\"}"}`,
+			testSensorSID)), true
+	})
+
+	_, err := trans.RequestReadDevice(otherSID)
+	require.ErrorContains(t, err, "request timed out")
+
+	sim.SetHook(nil)
+
+	info, err := trans.RequestReadDevice(otherSID)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"temperature":"1800"}`, info.Data)
+}
+
+func TestRetryPolicyWithoutJitter(t *testing.T) {
+	policy := RetryPolicy{Backoff: 100 * time.Millisecond}.withDefaults()
+	require.Equal(t, DefaultRetryPolicy().Attempts, policy.Attempts)
+	require.Zero(t, policy.Jitter)
+
+	for attempt := 0; attempt < 5; attempt++ {
+		require.Equal(t, 100*time.Millisecond<<attempt, policy.Delay(attempt))
+	}
+	require.Equal(t, DefaultRetryPolicy().MaxBackoff, policy.Delay(10))
+}
@@ -3,75 +3,464 @@ package xiaomi
 import (
 	"fmt"
 	"log"
+	"sync"
+	"time"
 
 	"github.com/cherserver/infocenter/service/devices"
 	"github.com/cherserver/infocenter/service/devices/xiaomi/device"
 	"github.com/cherserver/infocenter/service/devices/xiaomi/transport"
 )
 
-var _ devices.Sensors = &Gateway{}
+const (
+	defaultRescanInterval = 5 * time.Minute
+	minRescanInterval     = 30 * time.Second
 
-func NewGateway(addr string, token string) (*Gateway, error) {
-	trans, err := transport.New(addr, token)
+	// livenessCheckInterval is short enough to notice the gateway's own 10 seconds heartbeats missing.
+	livenessCheckInterval = 5 * time.Second
+)
+
+var (
+	_ devices.Source          = &Gateway{}
+	_ devices.ChangeNotifier  = &Gateway{}
+	_ devices.EventNotifier   = &Gateway{}
+	_ devices.ReadingNotifier = &Gateway{}
+)
+
+// connectPolicy spaces out attempts to reach an unavailable gateway, the service keeps running meanwhile.
+var connectPolicy = transport.RetryPolicy{
+	Backoff:    time.Second,
+	MaxBackoff: time.Minute,
+	Jitter:     0.2,
+}
+
+type Config struct {
+	Transport transport.Config
+
+	// RescanInterval is how often the child devices list is requested to notice paired and removed devices,
+	// 5 minutes when zero.
+	RescanInterval time.Duration
+}
+
+func NewGateway(cfg Config) (*Gateway, error) {
+	trans, err := transport.New(cfg.Transport)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode gateway token: %w", err)
+		return nil, fmt.Errorf("failed to create gateway transport: %w", err)
+	}
+
+	if cfg.RescanInterval == 0 {
+		cfg.RescanInterval = defaultRescanInterval
 	}
 
 	return &Gateway{
-		transport: trans,
+		name:              cfg.Transport.Name,
+		transport:         trans,
+		rescanInterval:    cfg.RescanInterval,
+		minRescanInterval: minRescanInterval,
+		children:          make(map[string]devices.Device),
+		foreignSIDs:       make(map[string]struct{}),
+		rescanRequests:    make(chan string, 1),
+		stopped:           make(chan struct{}),
+		done:              make(chan struct{}),
 	}, nil
 }
 
+// livenessChecker is a device telling its liveness changes.
+type livenessChecker interface {
+	CheckLiveness() (devices.Liveness, bool)
+}
+
+// readingsReplayer is a device able to report its readings again, as they are first reported before it is added.
+type readingsReplayer interface {
+	ReplayReadings()
+}
+
 type Gateway struct {
-	transport    *transport.Transport
-	childDevices []interface{}
+	name           string
+	transport      *transport.Transport
+	rescanInterval time.Duration
+	// minRescanInterval spaces out rescans requested by events from unknown devices
+	minRescanInterval time.Duration
+
+	children      map[string]devices.Device
+	childrenOrder []string
+	foreignSIDs   map[string]struct{} // SIDs of other gateways devices heard via multicast
+	childrenMutex sync.Mutex
+
+	changeConsumers      []devices.ChangeConsumeFunc
+	changeConsumersMutex sync.Mutex
+
+	eventConsumers      []devices.EventConsumeFunc
+	eventConsumersMutex sync.Mutex
+
+	readingConsumers      []devices.ReadingConsumeFunc
+	readingConsumersMutex sync.Mutex
+
+	rescanRequests chan string
+
+	stopped chan struct{}
+	done    chan struct{}
+}
+
+func (g *Gateway) Name() string {
+	return g.name
+}
+
+func (g *Gateway) Devices() []devices.Device {
+	g.childrenMutex.Lock()
+	defer g.childrenMutex.Unlock()
+
+	result := make([]devices.Device, 0, len(g.childrenOrder))
+	for _, sid := range g.childrenOrder {
+		result = append(result, g.children[sid])
+	}
+
+	return result
 }
 
-func (g *Gateway) Sensors() []interface{} {
-	return g.childDevices
+func (g *Gateway) RegisterChangeConsumer(consumeFunc devices.ChangeConsumeFunc) {
+	g.changeConsumersMutex.Lock()
+	defer g.changeConsumersMutex.Unlock()
+
+	g.changeConsumers = append(g.changeConsumers, consumeFunc)
 }
 
+// RegisterEventConsumer registers the consumer of events of all the gateway devices.
+func (g *Gateway) RegisterEventConsumer(consumeFunc devices.EventConsumeFunc) {
+	g.eventConsumersMutex.Lock()
+	defer g.eventConsumersMutex.Unlock()
+
+	g.eventConsumers = append(g.eventConsumers, consumeFunc)
+}
+
+// RegisterReadingConsumer registers the consumer of readings of all the gateway devices.
+func (g *Gateway) RegisterReadingConsumer(consumeFunc devices.ReadingConsumeFunc) {
+	g.readingConsumersMutex.Lock()
+	defer g.readingConsumersMutex.Unlock()
+
+	g.readingConsumers = append(g.readingConsumers, consumeFunc)
+}
+
+// Init starts the transport and connects to the gateway in background, retrying until it is reachable.
 func (g *Gateway) Init() error {
 	err := g.transport.Start()
 	if err != nil {
-		return fmt.Errorf("failed to start transpot: %w", err)
+		return fmt.Errorf("failed to start transport: %w", err)
+	}
+
+	g.transport.RegisterUnknownDeviceConsumer(g.onUnknownDevice)
+
+	go g.worker()
+
+	return nil
+}
+
+func (g *Gateway) Stop() {
+	close(g.stopped)
+	<-g.done
+
+	_ = g.transport.Stop()
+}
+
+func (g *Gateway) worker() {
+	defer close(g.done)
+
+	if !g.connect() {
+		return
 	}
 
-	devices, err := g.transport.RequestGetChildDevicesIDs()
+	ticker := time.NewTicker(g.rescanInterval)
+	defer ticker.Stop()
+
+	livenessTicker := time.NewTicker(livenessCheckInterval)
+	defer livenessTicker.Stop()
+
+	var lastRescanAt time.Time
+	// a rescan requested too early is postponed, so a newly paired device doesn't wait for the periodic one
+	var pendingSID string
+	var pendingRescan <-chan time.Time
+	for {
+		unknownSID := ""
+		select {
+		case <-g.stopped:
+			return
+		case <-livenessTicker.C:
+			g.checkLiveness()
+			continue
+		case <-ticker.C:
+		case unknownSID = <-g.rescanRequests:
+			if wait := g.minRescanInterval - time.Since(lastRescanAt); wait > 0 {
+				pendingSID = unknownSID
+				if pendingRescan == nil {
+					pendingRescan = time.After(wait)
+				}
+				continue
+			}
+		case <-pendingRescan:
+			unknownSID, pendingSID, pendingRescan = pendingSID, "", nil
+		}
+
+		if unknownSID != "" {
+			log.Printf("Gateway '%v' got event from unknown device '%v', rescanning", g.name, unknownSID)
+		}
+
+		lastRescanAt = time.Now()
+		err := g.rescan(unknownSID)
+		if err != nil {
+			log.Printf("Failed to rescan gateway '%v' devices: %v", g.name, err)
+		}
+	}
+}
+
+// connect retries until the gateway is connected and its devices are listed, false means the gateway was stopped.
+func (g *Gateway) connect() bool {
+	connected := false
+	for attempt := 0; ; attempt++ {
+		var err error
+		if !connected {
+			err = g.transport.Connect()
+			connected = err == nil
+		}
+
+		if connected {
+			err = g.rescan("")
+		}
+
+		if err == nil {
+			log.Printf("Gateway '%v' successfully started", g.name)
+			return true
+		}
+
+		delay := connectPolicy.Delay(attempt)
+		log.Printf("Failed to initialize gateway '%v': %v, retrying in %v", g.name, err, delay)
+
+		select {
+		case <-g.stopped:
+			return false
+		case <-time.After(delay):
+		}
+	}
+}
+
+// rescan adds the child devices not known yet and removes the ones gone from the gateway,
+// devices failed to be read are logged and left for the next rescan, so only failing to get the list is an error.
+// The unknown SID which caused the rescan is remembered as other gateway device when it is not in the list.
+func (g *Gateway) rescan(unknownSID string) error {
+	deviceSIDs, err := g.transport.RequestGetChildDevicesIDs()
 	if err != nil {
 		return fmt.Errorf("failed to get devices: %w", err)
 	}
 
-	log.Printf("Devices: %v", devices)
-	for _, deviceSID := range devices {
-		deviceInfo, err := g.transport.RequestReadDevice(deviceSID)
+	// the gateway is listed first as a device of its own
+	deviceSIDs = append([]string{g.transport.GatewaySID()}, deviceSIDs...)
+
+	present := make(map[string]struct{}, len(deviceSIDs))
+	for _, deviceSID := range deviceSIDs {
+		present[deviceSID] = struct{}{}
+	}
+
+	for _, sid := range g.missingDevices(present, unknownSID) {
+		g.removeDevice(sid)
+	}
+
+	for _, deviceSID := range deviceSIDs {
+		if g.isKnown(deviceSID) {
+			continue
+		}
+
+		err = g.readDevice(deviceSID)
 		if err != nil {
-			return fmt.Errorf("failed to read device '%v' info: %w", deviceSID, err)
+			log.Printf("Gateway '%v' failed to add device, left for the next rescan: %v", g.name, err)
 		}
+	}
 
-		switch deviceInfo.Model {
-		case "sensor_ht":
-			sensor, err := device.NewSensorHT(g.transport, deviceSID, deviceInfo.Data)
-			if err != nil {
-				return fmt.Errorf("failed to create sensor_ht: %w", err)
-			}
+	return nil
+}
 
-			g.childDevices = append(g.childDevices, sensor)
-		case "weather.v1":
-			sensor, err := device.NewWeatherV1(g.transport, deviceSID, deviceInfo.Data)
-			if err != nil {
-				return fmt.Errorf("failed to create weather.v1: %w", err)
-			}
+func (g *Gateway) readDevice(deviceSID string) error {
+	deviceInfo, err := g.transport.RequestReadDevice(deviceSID)
+	if err != nil {
+		return fmt.Errorf("failed to read device '%v' info: %w", deviceSID, err)
+	}
 
-			g.childDevices = append(g.childDevices, sensor)
-		}
+	dev, err := device.New(g.transport, deviceSID, deviceInfo.Model, deviceInfo.Data)
+	if err != nil {
+		return err
 	}
 
-	log.Printf("Gateway successfully started")
+	g.addDevice(dev)
 
 	return nil
 }
 
-func (g *Gateway) Stop() {
-	_ = g.transport.Stop()
+// checkLiveness notifies about the devices went stale, offline or back online.
+func (g *Gateway) checkLiveness() {
+	for _, dev := range g.Devices() {
+		checker, ok := dev.(livenessChecker)
+		if !ok {
+			continue
+		}
+
+		liveness, changed := checker.CheckLiveness()
+		if !changed {
+			continue
+		}
+
+		log.Printf("Gateway '%v' device '%v' is %v", g.name, dev.SID(), liveness)
+
+		g.notifyChange(devices.Change{
+			Kind:     devices.DeviceLivenessChanged,
+			SID:      dev.SID(),
+			Model:    dev.Model(),
+			Gateway:  g.name,
+			Device:   dev,
+			Liveness: liveness,
+		})
+	}
+}
+
+func (g *Gateway) onUnknownDevice(sid string, _ string) {
+	if g.isKnown(sid) || g.isForeign(sid) {
+		return
+	}
+
+	select {
+	case g.rescanRequests <- sid:
+	default:
+	}
+}
+
+func (g *Gateway) isKnown(sid string) bool {
+	g.childrenMutex.Lock()
+	defer g.childrenMutex.Unlock()
+
+	_, fnd := g.children[sid]
+	return fnd
+}
+
+func (g *Gateway) isForeign(sid string) bool {
+	g.childrenMutex.Lock()
+	defer g.childrenMutex.Unlock()
+
+	_, fnd := g.foreignSIDs[sid]
+	return fnd
+}
+
+// missingDevices returns the known SIDs missing from the present ones.
+func (g *Gateway) missingDevices(present map[string]struct{}, unknownSID string) []string {
+	g.childrenMutex.Lock()
+	defer g.childrenMutex.Unlock()
+
+	if _, fnd := present[unknownSID]; !fnd && unknownSID != "" {
+		g.foreignSIDs[unknownSID] = struct{}{}
+	}
+
+	removed := make([]string, 0)
+	for _, sid := range g.childrenOrder {
+		if _, fnd := present[sid]; !fnd {
+			removed = append(removed, sid)
+		}
+	}
+
+	return removed
+}
+
+// addDevice notifies about the device before attaching its readings, so they are calibrated when reported,
+// and replays the readings reported before.
+func (g *Gateway) addDevice(dev devices.Device) {
+	if notifier, ok := dev.(devices.EventNotifier); ok {
+		notifier.RegisterEventConsumer(g.notifyEvent)
+	}
+
+	func() {
+		g.childrenMutex.Lock()
+		defer g.childrenMutex.Unlock()
+
+		g.children[dev.SID()] = dev
+		g.childrenOrder = append(g.childrenOrder, dev.SID())
+		delete(g.foreignSIDs, dev.SID())
+	}()
+
+	log.Printf("Gateway '%v' device '%v' of model '%v' added", g.name, dev.SID(), dev.Model())
+
+	g.notifyChange(devices.Change{
+		Kind:    devices.DeviceAdded,
+		SID:     dev.SID(),
+		Model:   dev.Model(),
+		Gateway: g.name,
+		Device:  dev,
+	})
+
+	if notifier, ok := dev.(devices.ReadingNotifier); ok {
+		notifier.RegisterReadingConsumer(g.notifyReading)
+	}
+
+	if replayer, ok := dev.(readingsReplayer); ok {
+		replayer.ReplayReadings()
+	}
+}
+
+func (g *Gateway) removeDevice(sid string) {
+	dev := func() devices.Device {
+		g.childrenMutex.Lock()
+		defer g.childrenMutex.Unlock()
+
+		dev, fnd := g.children[sid]
+		if !fnd {
+			return nil
+		}
+
+		delete(g.children, sid)
+		for idx, orderSID := range g.childrenOrder {
+			if orderSID == sid {
+				g.childrenOrder = append(g.childrenOrder[:idx:idx], g.childrenOrder[idx+1:]...)
+				break
+			}
+		}
+
+		return dev
+	}()
+	if dev == nil {
+		return
+	}
+
+	g.transport.UnregisterConsumers(sid)
+	log.Printf("Gateway '%v' device '%v' of model '%v' removed", g.name, sid, dev.Model())
+
+	g.notifyChange(devices.Change{
+		Kind:    devices.DeviceRemoved,
+		SID:     sid,
+		Model:   dev.Model(),
+		Gateway: g.name,
+		Device:  dev,
+	})
+}
+
+func (g *Gateway) notifyChange(change devices.Change) {
+	g.changeConsumersMutex.Lock()
+	consumers := append([]devices.ChangeConsumeFunc(nil), g.changeConsumers...)
+	g.changeConsumersMutex.Unlock()
+
+	for _, consumeFunc := range consumers {
+		consumeFunc(change)
+	}
+}
+
+func (g *Gateway) notifyEvent(event devices.Event) {
+	g.eventConsumersMutex.Lock()
+	consumers := append([]devices.EventConsumeFunc(nil), g.eventConsumers...)
+	g.eventConsumersMutex.Unlock()
+
+	for _, consumeFunc := range consumers {
+		consumeFunc(event)
+	}
+}
+
+func (g *Gateway) notifyReading(reading devices.Reading) {
+	g.readingConsumersMutex.Lock()
+	consumers := append([]devices.ReadingConsumeFunc(nil), g.readingConsumers...)
+	g.readingConsumersMutex.Unlock()
+
+	for _, consumeFunc := range consumers {
+		consumeFunc(reading)
+	}
 }
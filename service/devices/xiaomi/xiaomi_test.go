@@ -0,0 +1,206 @@
+package xiaomi
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cherserver/infocenter/service/devices"
+	"github.com/cherserver/infocenter/service/devices/xiaomi/internal/simtest"
+	"github.com/cherserver/infocenter/service/devices/xiaomi/simulator"
+	"github.com/cherserver/infocenter/service/devices/xiaomi/transport"
+)
+
+const (
+	testSensorSID    = "158d0001fd4989"
+	testNewSensorSID = "158d0001f57fee"
+)
+
+// newSimulatedGateway creates a gateway connecting to a started simulator, the gateway is not initialized.
+func newSimulatedGateway(t *testing.T, cfg simulator.Config) (*simulator.Simulator, *Gateway) {
+	sim := simtest.Start(t, cfg)
+
+	gateway, err := NewGateway(Config{
+		Transport: transport.Config{
+			Name:           "test",
+			Address:        "127.0.0.1",
+			Port:           sim.Addr().Port,
+			Token:          simtest.Token,
+			EventsAddress:  sim.EventsAddr().String(),
+			RequestTimeout: 200 * time.Millisecond,
+			Retry:          transport.RetryPolicy{Attempts: 1},
+		},
+		RescanInterval: time.Hour,
+	})
+	require.NoError(t, err)
+
+	return sim, gateway
+}
+
+func sensorHT(sid string) simulator.Device {
+	return simulator.Device{
+		SID:   sid,
+		Model: "sensor_ht",
+		Data:  map[string]interface{}{"voltage": 3005, "temperature": "2150", "humidity": "4520"},
+	}
+}
+
+func TestHotPlug(t *testing.T) {
+	sim, gateway := newSimulatedGateway(t, simulator.Config{
+		Devices: []simulator.Device{sensorHT(testSensorSID)},
+	})
+
+	changes := make(chan devices.Change, 3)
+	gateway.RegisterChangeConsumer(func(change devices.Change) {
+		changes <- change
+	})
+
+	require.NoError(t, gateway.Init())
+	t.Cleanup(gateway.Stop)
+
+	awaitChange := func(kind devices.ChangeKind, sid string) {
+		select {
+		case change := <-changes:
+			require.Equal(t, kind, change.Kind)
+			require.Equal(t, sid, change.SID)
+			require.Equal(t, "test", change.Gateway)
+		case <-time.After(2 * time.Second):
+			require.Failf(t, "change was not notified", "%v %v", kind, sid)
+		}
+	}
+
+	awaitChange(devices.DeviceAdded, sim.SID())
+	awaitChange(devices.DeviceAdded, testSensorSID)
+	require.Len(t, gateway.Devices(), 2)
+
+	sim.AddDevice(sensorHT(testNewSensorSID))
+	require.NoError(t, sim.Report(testNewSensorSID, map[string]interface{}{"temperature": "1990"}))
+
+	awaitChange(devices.DeviceAdded, testNewSensorSID)
+	require.Len(t, gateway.Devices(), 3)
+
+	sim.RemoveDevice(testSensorSID)
+	require.NoError(t, gateway.rescan(""))
+
+	awaitChange(devices.DeviceRemoved, testSensorSID)
+	require.Len(t, gateway.Devices(), 2)
+}
+
+func TestGatewayLight(t *testing.T) {
+	_, gateway := newSimulatedGateway(t, simulator.Config{
+		GatewayData: map[string]interface{}{"rgb": 0, "illumination": 1250},
+	})
+	require.NoError(t, gateway.Init())
+	t.Cleanup(gateway.Stop)
+
+	require.Eventually(t, func() bool {
+		return len(gateway.Devices()) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+
+	light, ok := gateway.Devices()[0].(devices.RGBLight)
+	require.True(t, ok)
+	require.Zero(t, light.Brightness())
+	require.InDelta(t, 1250, gateway.Devices()[0].(devices.Illuminometer).Illuminance(), 0.0001)
+
+	require.NoError(t, light.SetLight(0xff8000, 40))
+	require.Equal(t, uint32(0xff8000), light.RGB())
+	require.Equal(t, uint8(40), light.Brightness())
+
+	require.Error(t, light.SetLight(0xff8000, 101))
+}
+
+func TestPostponedRescan(t *testing.T) {
+	sim, gateway := newSimulatedGateway(t, simulator.Config{})
+	gateway.minRescanInterval = 500 * time.Millisecond
+
+	require.NoError(t, gateway.Init())
+	t.Cleanup(gateway.Stop)
+
+	require.Eventually(t, func() bool {
+		return len(gateway.Devices()) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+
+	sim.AddDevice(sensorHT(testSensorSID))
+	require.NoError(t, sim.Report(testSensorSID, map[string]interface{}{"temperature": "1990"}))
+	require.Eventually(t, func() bool {
+		return gateway.isKnown(testSensorSID)
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// the second device is heard right after the rescan, it is picked up once the interval passes
+	sim.AddDevice(sensorHT(testNewSensorSID))
+	require.NoError(t, sim.Report(testNewSensorSID, map[string]interface{}{"temperature": "1990"}))
+	require.Eventually(t, func() bool {
+		return gateway.isKnown(testNewSensorSID)
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestUnreadableDevice(t *testing.T) {
+	sim, gateway := newSimulatedGateway(t, simulator.Config{
+		Devices: []simulator.Device{sensorHT(testSensorSID), sensorHT(testNewSensorSID)},
+	})
+
+	// reading the second device never gets an answer
+	sim.SetHook(func(req simulator.Request) ([]byte, bool) {
+		return nil, req.Cmd == "read" && req.SID == testNewSensorSID
+	})
+
+	require.NoError(t, gateway.transport.Start())
+	t.Cleanup(func() { _ = gateway.transport.Stop() })
+
+	connected := make(chan bool, 1)
+	go func() { connected <- gateway.connect() }()
+
+	select {
+	case ok := <-connected:
+		require.True(t, ok)
+	case <-time.After(2 * time.Second):
+		close(gateway.stopped)
+		require.Fail(t, "gateway is not connected because of the unreadable device")
+	}
+
+	require.True(t, gateway.isKnown(testSensorSID))
+	require.False(t, gateway.isKnown(testNewSensorSID))
+
+	sim.SetHook(nil)
+	require.NoError(t, gateway.rescan(""))
+	require.True(t, gateway.isKnown(testNewSensorSID))
+}
+
+func TestInitReadings(t *testing.T) {
+	_, gateway := newSimulatedGateway(t, simulator.Config{
+		Devices: []simulator.Device{sensorHT(testSensorSID)},
+	})
+
+	// calibrations are applied when devices are added, the way the calibrations store does
+	gateway.RegisterChangeConsumer(func(change devices.Change) {
+		if calibratable, ok := change.Device.(devices.Calibratable); ok && change.Kind == devices.DeviceAdded {
+			calibratable.SetCalibration(devices.Calibration{TemperatureOffset: -0.5})
+		}
+	})
+
+	readings := make(chan devices.Reading, 2)
+	gateway.RegisterReadingConsumer(func(reading devices.Reading) {
+		if reading.SID == testSensorSID {
+			readings <- reading
+		}
+	})
+
+	require.NoError(t, gateway.Init())
+	t.Cleanup(gateway.Stop)
+
+	values := make(map[devices.Metric]float32)
+	for len(values) < 2 {
+		select {
+		case reading := <-readings:
+			values[reading.Metric] = reading.Value
+		case <-time.After(2 * time.Second):
+			require.Fail(t, "init data readings were not reported")
+		}
+	}
+
+	require.Equal(t, map[devices.Metric]float32{
+		devices.MetricTemperature: 21,
+		devices.MetricHumidity:    45.2,
+	}, values)
+}
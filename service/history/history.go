@@ -0,0 +1,645 @@
+package history
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cherserver/infocenter/service/devices"
+)
+
+const (
+	defaultRawRetention      = 48 * time.Hour
+	defaultAveragesRetention = 30 * 24 * time.Hour
+
+	averagesStep = 5 * time.Minute
+	hourlyStep   = time.Hour
+
+	flushInterval = time.Minute
+
+	dailySegments   = "2006-01-02"
+	monthlySegments = "2006-01"
+)
+
+type Config struct {
+	Dir string
+
+	// RawRetention is how long every reading is kept, 48 hours when zero. The buckets not written
+	// before a restart are rebuilt from the readings, so it is expected to be at least an hour.
+	RawRetention time.Duration
+	// AveragesRetention is how long the 5-minute averages are kept, 30 days when zero.
+	// Hourly averages are kept forever.
+	AveragesRetention time.Duration
+}
+
+// Sample is a reading or the readings of a time bucket aggregated.
+type Sample struct {
+	SID    string
+	Metric devices.Metric
+	At     time.Time // start of the bucket for aggregated samples
+	Avg    float32
+	Min    float32
+	Max    float32
+	Count  int
+}
+
+func (s *Sample) add(other Sample) {
+	if s.Count == 0 {
+		*s = other
+		return
+	}
+
+	total := s.Count + other.Count
+	s.Avg = (s.Avg*float32(s.Count) + other.Avg*float32(other.Count)) / float32(total)
+	s.Count = total
+
+	if other.Min < s.Min {
+		s.Min = other.Min
+	}
+
+	if other.Max > s.Max {
+		s.Max = other.Max
+	}
+}
+
+type seriesKey struct {
+	sid    string
+	metric devices.Metric
+}
+
+// Store records the device readings into append-only segment files of every tier: the raw readings
+// and their averages by 5 minutes and by hour. Segments older than the tier retention are removed.
+type Store struct {
+	tiers  []*tier
+	recent map[seriesKey][]Sample // raw samples of the last TrendWindow
+	mutex  sync.Mutex
+
+	stopped chan struct{}
+	done    chan struct{}
+}
+
+func New(cfg Config) *Store {
+	if cfg.RawRetention == 0 {
+		cfg.RawRetention = defaultRawRetention
+	}
+
+	if cfg.AveragesRetention == 0 {
+		cfg.AveragesRetention = defaultAveragesRetention
+	}
+
+	return &Store{
+		tiers: []*tier{
+			newTier(filepath.Join(cfg.Dir, "raw"), 0, cfg.RawRetention, dailySegments),
+			newTier(filepath.Join(cfg.Dir, "5m"), averagesStep, cfg.AveragesRetention, dailySegments),
+			newTier(filepath.Join(cfg.Dir, "1h"), hourlyStep, 0, monthlySegments),
+		},
+		recent:  make(map[seriesKey][]Sample),
+		stopped: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+func (s *Store) Init() error {
+	for _, t := range s.tiers {
+		err := os.MkdirAll(t.dir, 0o755)
+		if err != nil {
+			return fmt.Errorf("failed to create history directory: %w", err)
+		}
+	}
+
+	s.prune(time.Now())
+
+	err := s.restore(time.Now())
+	if err != nil {
+		return err
+	}
+
+	go s.worker()
+
+	log.Printf("History started")
+	return nil
+}
+
+func (s *Store) Stop() {
+	close(s.stopped)
+	<-s.done
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// buckets not ended yet are restored from the raw readings on the next start
+	now := time.Now()
+	for _, t := range s.tiers {
+		t.flush(now)
+		t.close()
+	}
+
+	log.Printf("History stopped")
+}
+
+// Record stores the reading in every tier.
+func (s *Store) Record(reading devices.Reading) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	sample := Sample{
+		SID:    reading.SID,
+		Metric: reading.Metric,
+		At:     reading.At,
+		Avg:    reading.Value,
+		Min:    reading.Value,
+		Max:    reading.Value,
+		Count:  1,
+	}
+
+	for _, t := range s.tiers {
+		t.record(sample)
+	}
+
+	s.remember(sample)
+}
+
+// restore rebuilds the state lost by a restart or a crash from the raw readings: the recent samples
+// and the buckets of every series after the last one written.
+func (s *Store) restore(now time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	raw := s.tiers[0]
+	from := now.Add(-raw.retention)
+	readings, err := raw.query(nil, from, now)
+	if err != nil {
+		return fmt.Errorf("failed to load recent history: %w", err)
+	}
+
+	for _, reading := range readings {
+		if !reading.At.Before(now.Add(-TrendWindow)) {
+			s.remember(reading)
+		}
+	}
+
+	for _, t := range s.tiers[1:] {
+		written, err := t.read(nil, from.Truncate(t.step), now)
+		if err != nil {
+			return fmt.Errorf("failed to load recent history: %w", err)
+		}
+
+		for _, sample := range written {
+			t.markWritten(sample)
+		}
+
+		// the readings of the buckets written already are ignored
+		for _, reading := range readings {
+			t.record(reading)
+		}
+
+		t.flush(now)
+	}
+
+	return nil
+}
+
+// Query returns the samples of the device metric in [from, to) ordered by time from the finest tier
+// still keeping the from moment. Segment files are read without the lock, so recording is not delayed.
+func (s *Store) Query(sid string, metric devices.Metric, from time.Time, to time.Time) ([]Sample, error) {
+	key := seriesKey{sid: sid, metric: metric}
+
+	s.mutex.Lock()
+	t := s.tierFor(from, time.Now())
+	open := t.openBuckets(&key, from, to)
+	s.mutex.Unlock()
+
+	written, err := t.read(&key, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeSamples(written, open), nil
+}
+
+func (s *Store) tierFor(from time.Time, now time.Time) *tier {
+	for _, t := range s.tiers {
+		if t.retention == 0 || !from.Before(now.Add(-t.retention)) {
+			return t
+		}
+	}
+
+	return s.tiers[len(s.tiers)-1]
+}
+
+func (s *Store) worker() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stopped:
+			return
+		case now := <-ticker.C:
+			s.mutex.Lock()
+			for _, t := range s.tiers {
+				t.flush(now)
+			}
+			s.mutex.Unlock()
+
+			s.prune(now)
+		}
+	}
+}
+
+func (s *Store) prune(now time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, t := range s.tiers {
+		err := t.prune(now)
+		if err != nil {
+			log.Printf("Failed to prune history: %v", err)
+		}
+	}
+}
+
+// tier is a series of segment files, every file keeps the samples of a day or a month.
+type tier struct {
+	dir           string
+	step          time.Duration // zero for the raw readings
+	retention     time.Duration // zero keeps the samples forever
+	segmentLayout string
+
+	buckets      map[seriesKey]*Sample   // being aggregated
+	writtenUntil map[seriesKey]time.Time // end of the last bucket written, later samples of it are ignored
+
+	file    *os.File
+	segment string
+}
+
+func newTier(dir string, step time.Duration, retention time.Duration, segmentLayout string) *tier {
+	return &tier{
+		dir:           dir,
+		step:          step,
+		retention:     retention,
+		segmentLayout: segmentLayout,
+		buckets:       make(map[seriesKey]*Sample),
+		writtenUntil:  make(map[seriesKey]time.Time),
+	}
+}
+
+func (t *tier) record(sample Sample) {
+	if t.step == 0 {
+		t.write(sample)
+		return
+	}
+
+	key := seriesKey{sid: sample.SID, metric: sample.Metric}
+	start := sample.At.Truncate(t.step)
+
+	// late samples of the buckets written or followed by the open one are dropped
+	if start.Before(t.writtenUntil[key]) {
+		return
+	}
+
+	bucket, fnd := t.buckets[key]
+	if fnd && start.Before(bucket.At) {
+		return
+	}
+
+	if fnd && start.After(bucket.At) {
+		t.write(*bucket)
+		fnd = false
+	}
+
+	if !fnd {
+		bucket = &Sample{}
+		t.buckets[key] = bucket
+	}
+
+	bucket.add(sample)
+	bucket.At = start
+}
+
+// flush writes the buckets ended before now.
+func (t *tier) flush(now time.Time) {
+	for key, bucket := range t.buckets {
+		if !bucket.At.Add(t.step).After(now) {
+			t.write(*bucket)
+			delete(t.buckets, key)
+		}
+	}
+}
+
+// markWritten remembers the bucket is written and returns false if it was written already.
+func (t *tier) markWritten(bucket Sample) bool {
+	key := seriesKey{sid: bucket.SID, metric: bucket.Metric}
+	if bucket.At.Before(t.writtenUntil[key]) {
+		return false
+	}
+
+	t.writtenUntil[key] = bucket.At.Add(t.step)
+	return true
+}
+
+func (t *tier) write(sample Sample) {
+	if t.step != 0 && !t.markWritten(sample) {
+		log.Printf("Skipping history bucket of '%v' %v at %v written already", sample.SID, sample.Metric, sample.At)
+		return
+	}
+
+	segment := sample.At.UTC().Format(t.segmentLayout)
+	if segment != t.segment {
+		t.close()
+
+		file, err := os.OpenFile(t.segmentPath(segment), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			log.Printf("Failed to open history segment: %v", err)
+			return
+		}
+
+		t.file = file
+		t.segment = segment
+	}
+
+	_, err := t.file.WriteString(formatSample(sample, t.step == 0))
+	if err != nil {
+		log.Printf("Failed to write history segment '%v': %v", t.file.Name(), err)
+	}
+}
+
+func (t *tier) close() {
+	if t.file == nil {
+		return
+	}
+
+	_ = t.file.Close()
+	t.file = nil
+	t.segment = ""
+}
+
+func (t *tier) segmentPath(segment string) string {
+	return filepath.Join(t.dir, segment+".log")
+}
+
+// segmentEnd returns the moment the segment started at start ends.
+func (t *tier) segmentEnd(start time.Time) time.Time {
+	if t.segmentLayout == monthlySegments {
+		return start.AddDate(0, 1, 0)
+	}
+
+	return start.AddDate(0, 0, 1)
+}
+
+func (t *tier) segments() ([]time.Time, error) {
+	entries, err := os.ReadDir(t.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list history segments: %w", err)
+	}
+
+	starts := make([]time.Time, 0, len(entries))
+	for _, entry := range entries {
+		name, fnd := strings.CutSuffix(entry.Name(), ".log")
+		if !fnd {
+			continue
+		}
+
+		start, err := time.Parse(t.segmentLayout, name)
+		if err != nil {
+			continue
+		}
+
+		starts = append(starts, start)
+	}
+
+	sort.Slice(starts, func(i, j int) bool {
+		return starts[i].Before(starts[j])
+	})
+
+	return starts, nil
+}
+
+func (t *tier) prune(now time.Time) error {
+	if t.retention == 0 {
+		return nil
+	}
+
+	starts, err := t.segments()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, start := range starts {
+		if t.segmentEnd(start).After(now.Add(-t.retention)) {
+			break
+		}
+
+		segment := start.Format(t.segmentLayout)
+		if segment == t.segment {
+			t.close()
+		}
+
+		err = os.Remove(t.segmentPath(segment))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove history segment: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// query returns the samples of the series in [from, to) ordered by time, nil key selects every series.
+func (t *tier) query(key *seriesKey, from time.Time, to time.Time) ([]Sample, error) {
+	written, err := t.read(key, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeSamples(written, t.openBuckets(key, from, to)), nil
+}
+
+// read returns the written samples of the series in [from, to), it doesn't touch the tier state.
+func (t *tier) read(key *seriesKey, from time.Time, to time.Time) ([]Sample, error) {
+	starts, err := t.segments()
+	if err != nil {
+		return nil, err
+	}
+
+	var samples []Sample
+	for _, start := range starts {
+		if !t.segmentEnd(start).After(from) || !start.Before(to) {
+			continue
+		}
+
+		segmentSamples, err := t.readSegment(start.Format(t.segmentLayout), key, from, to)
+		if err != nil {
+			return nil, err
+		}
+
+		samples = append(samples, segmentSamples...)
+	}
+
+	return samples, nil
+}
+
+// openBuckets returns copies of the buckets of the series in [from, to) being aggregated.
+func (t *tier) openBuckets(key *seriesKey, from time.Time, to time.Time) []Sample {
+	var samples []Sample
+	for bucketKey, bucket := range t.buckets {
+		if (key == nil || bucketKey == *key) && !bucket.At.Before(from) && bucket.At.Before(to) {
+			samples = append(samples, *bucket)
+		}
+	}
+
+	return samples
+}
+
+// mergeSamples orders the written samples and the open buckets by time. A bucket written after it was copied
+// as open is read twice, so the written samples of the open buckets are skipped.
+func mergeSamples(written []Sample, open []Sample) []Sample {
+	openKeys := make(map[seriesKey]time.Time, len(open))
+	for _, bucket := range open {
+		openKeys[seriesKey{sid: bucket.SID, metric: bucket.Metric}] = bucket.At
+	}
+
+	samples := make([]Sample, 0, len(written)+len(open))
+	for _, sample := range written {
+		at, fnd := openKeys[seriesKey{sid: sample.SID, metric: sample.Metric}]
+		if fnd && at.Equal(sample.At) {
+			continue
+		}
+
+		samples = append(samples, sample)
+	}
+	samples = append(samples, open...)
+
+	sort.SliceStable(samples, func(i, j int) bool {
+		return samples[i].At.Before(samples[j].At)
+	})
+
+	return samples
+}
+
+func (t *tier) readSegment(segment string, key *seriesKey, from time.Time, to time.Time) ([]Sample, error) {
+	file, err := os.Open(t.segmentPath(segment))
+	if errors.Is(err, os.ErrNotExist) {
+		// pruned after being listed
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history segment: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	var samples []Sample
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		sample, err := parseSample(scanner.Text())
+		if err != nil {
+			// the last line may be partially written on a crash
+			continue
+		}
+
+		if key != nil && (sample.SID != key.sid || sample.Metric != key.metric) {
+			continue
+		}
+
+		if sample.At.Before(from) || !sample.At.Before(to) {
+			continue
+		}
+
+		samples = append(samples, sample)
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history segment '%v': %w", file.Name(), err)
+	}
+
+	return samples, nil
+}
+
+// formatSample formats the sample as a line of tab separated fields: time in Unix seconds, SID, metric,
+// then the value for raw readings or the average, minimum, maximum and count for aggregated ones.
+func formatSample(sample Sample, raw bool) string {
+	if raw {
+		return fmt.Sprintf("%d\t%s\t%s\t%v\n", sample.At.Unix(), sample.SID, sample.Metric, sample.Avg)
+	}
+
+	return fmt.Sprintf("%d\t%s\t%s\t%v\t%v\t%v\t%d\n",
+		sample.At.Unix(), sample.SID, sample.Metric, sample.Avg, sample.Min, sample.Max, sample.Count)
+}
+
+func parseSample(line string) (Sample, error) {
+	fields := strings.Split(line, "\t")
+	if len(fields) != 4 && len(fields) != 7 {
+		return Sample{}, fmt.Errorf("unexpected fields count %d", len(fields))
+	}
+
+	seconds, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return Sample{}, fmt.Errorf("failed to parse time: %w", err)
+	}
+
+	valueFields := fields[3:4]
+	if len(fields) == 7 {
+		valueFields = fields[3:6]
+	}
+
+	values := make([]float32, 0, len(valueFields))
+	for _, field := range valueFields {
+		value, err := strconv.ParseFloat(field, 32)
+		if err != nil {
+			return Sample{}, fmt.Errorf("failed to parse value: %w", err)
+		}
+
+		values = append(values, float32(value))
+	}
+
+	sample := Sample{
+		SID:    fields[1],
+		Metric: devices.Metric(fields[2]),
+		At:     time.Unix(seconds, 0),
+		Avg:    values[0],
+		Min:    values[0],
+		Max:    values[0],
+		Count:  1,
+	}
+
+	if len(fields) == 7 {
+		sample.Min = values[1]
+		sample.Max = values[2]
+
+		sample.Count, err = strconv.Atoi(fields[6])
+		if err != nil {
+			return Sample{}, fmt.Errorf("failed to parse count: %w", err)
+		}
+	}
+
+	return sample, nil
+}
+
+// Aggregate merges the samples ordered by time into buckets of the step, every bucket starts at the time
+// truncated to the step. Buckets without samples are omitted.
+func Aggregate(samples []Sample, step time.Duration) []Sample {
+	var buckets []Sample
+	for _, sample := range samples {
+		start := sample.At.Truncate(step)
+		if len(buckets) == 0 || !buckets[len(buckets)-1].At.Equal(start) {
+			buckets = append(buckets, Sample{})
+		}
+
+		bucket := &buckets[len(buckets)-1]
+		bucket.add(sample)
+		bucket.At = start
+	}
+
+	return buckets
+}
@@ -0,0 +1,249 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cherserver/infocenter/service/devices"
+)
+
+func reading(metric devices.Metric, value float32, at time.Time) devices.Reading {
+	return devices.Reading{SID: "158d0001f57fee", Metric: metric, Value: value, At: at}
+}
+
+func TestRecordAndQuery(t *testing.T) {
+	dir := t.TempDir()
+	store := New(Config{Dir: dir})
+	require.NoError(t, store.Init())
+
+	now := time.Now().Truncate(time.Second)
+	store.Record(reading(devices.MetricTemperature, 21.5, now.Add(-20*time.Minute)))
+	store.Record(reading(devices.MetricHumidity, 45, now.Add(-20*time.Minute)))
+	store.Record(reading(devices.MetricTemperature, 22, now.Add(-10*time.Minute)))
+
+	samples, err := store.Query("158d0001f57fee", devices.MetricTemperature, now.Add(-time.Hour), now)
+	require.NoError(t, err)
+	require.Len(t, samples, 2)
+	require.Equal(t, float32(21.5), samples[0].Avg)
+	require.True(t, now.Add(-20*time.Minute).Equal(samples[0].At))
+	require.Equal(t, float32(22), samples[1].Avg)
+
+	store.Stop()
+
+	reopened := New(Config{Dir: dir})
+	samples, err = reopened.Query("158d0001f57fee", devices.MetricTemperature, now.Add(-time.Hour), now)
+	require.NoError(t, err)
+	require.Len(t, samples, 2)
+
+	// the 5-minute averages are written on stop
+	samples, err = reopened.tiers[1].query(&seriesKey{sid: "158d0001f57fee", metric: devices.MetricTemperature},
+		now.Add(-time.Hour), now)
+	require.NoError(t, err)
+	require.Len(t, samples, 2)
+}
+
+func TestRestart(t *testing.T) {
+	dir := t.TempDir()
+	key := seriesKey{sid: "158d0001f57fee", metric: devices.MetricTemperature}
+
+	now := time.Now().Truncate(time.Second)
+	hour := now.Truncate(time.Hour).UTC()
+	previousHour := hour.Add(-time.Hour)
+	elapsed := now.Sub(hour)
+
+	store := New(Config{Dir: dir})
+	require.NoError(t, store.Init())
+	for minutes := 0; minutes < 60; minutes += 10 {
+		store.Record(reading(key.metric, 20, previousHour.Add(time.Duration(minutes)*time.Minute)))
+	}
+	for idx := 0; idx < 5; idx++ {
+		store.Record(reading(key.metric, 22, hour.Add(elapsed*time.Duration(idx)/10).Truncate(time.Second)))
+	}
+	store.Stop()
+
+	// the bucket of the current hour continues after the restart instead of being written twice
+	store = New(Config{Dir: dir})
+	require.NoError(t, store.Init())
+	for idx := 5; idx < 10; idx++ {
+		store.Record(reading(key.metric, 24, hour.Add(elapsed*time.Duration(idx)/10).Truncate(time.Second)))
+	}
+
+	samples, err := store.tiers[2].query(&key, previousHour, hour.Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, []Sample{
+		{SID: key.sid, Metric: key.metric, At: previousHour, Avg: 20, Min: 20, Max: 20, Count: 6},
+		{SID: key.sid, Metric: key.metric, At: hour, Avg: 23, Min: 22, Max: 24, Count: 10},
+	}, utcSamples(samples))
+	store.Stop()
+
+	store = New(Config{Dir: dir})
+	require.NoError(t, store.Init())
+	defer store.Stop()
+
+	samples, err = store.tiers[2].query(&key, previousHour, hour.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, samples, 2)
+	require.Equal(t, 10, samples[1].Count)
+}
+
+func TestCrashRecovery(t *testing.T) {
+	dir := t.TempDir()
+	key := seriesKey{sid: "158d0001f57fee", metric: devices.MetricTemperature}
+	previousHour := time.Now().Truncate(time.Hour).Add(-time.Hour).UTC()
+
+	store := New(Config{Dir: dir})
+	require.NoError(t, store.Init())
+	for minutes := 0; minutes < 60; minutes += 10 {
+		store.Record(reading(key.metric, float32(20+minutes/10), previousHour.Add(time.Duration(minutes)*time.Minute)))
+	}
+
+	// the process dies before the buckets are flushed
+	close(store.stopped)
+	<-store.done
+	for _, tier := range store.tiers {
+		tier.close()
+	}
+
+	store = New(Config{Dir: dir})
+	require.NoError(t, store.Init())
+	defer store.Stop()
+
+	// the ended buckets are rebuilt from the raw readings and written at once
+	store.mutex.Lock()
+	require.Empty(t, store.tiers[2].buckets)
+	store.mutex.Unlock()
+
+	samples, err := store.tiers[2].query(&key, previousHour, previousHour.Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, []Sample{
+		{SID: key.sid, Metric: key.metric, At: previousHour, Avg: 22.5, Min: 20, Max: 25, Count: 6},
+	}, utcSamples(samples))
+
+	samples, err = store.tiers[1].query(&key, previousHour, previousHour.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, samples, 6)
+}
+
+func TestDownsampling(t *testing.T) {
+	store := New(Config{Dir: t.TempDir()})
+	require.NoError(t, store.Init())
+	defer store.Stop()
+
+	start := time.Date(2023, 1, 10, 12, 0, 0, 0, time.UTC)
+	store.Record(reading(devices.MetricTemperature, 20, start.Add(time.Minute)))
+	store.Record(reading(devices.MetricTemperature, 22, start.Add(2*time.Minute)))
+	store.Record(reading(devices.MetricTemperature, 24, start.Add(4*time.Minute)))
+	store.Record(reading(devices.MetricTemperature, 30, start.Add(6*time.Minute)))
+
+	key := seriesKey{sid: "158d0001f57fee", metric: devices.MetricTemperature}
+	samples, err := store.tiers[1].query(&key, start, start.Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, []Sample{
+		{SID: key.sid, Metric: key.metric, At: start, Avg: 22, Min: 20, Max: 24, Count: 3},
+		{SID: key.sid, Metric: key.metric, At: start.Add(5 * time.Minute), Avg: 30, Min: 30, Max: 30, Count: 1},
+	}, utcSamples(samples))
+
+	samples, err = store.tiers[2].query(&key, start, start.Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, []Sample{
+		{SID: key.sid, Metric: key.metric, At: start, Avg: 24, Min: 20, Max: 30, Count: 4},
+	}, utcSamples(samples))
+}
+
+func TestOutOfOrderReadings(t *testing.T) {
+	store := New(Config{Dir: t.TempDir()})
+	require.NoError(t, store.Init())
+	defer store.Stop()
+
+	start := time.Date(2023, 1, 10, 12, 0, 0, 0, time.UTC)
+	store.Record(reading(devices.MetricTemperature, 20, start.Add(time.Minute)))
+	store.Record(reading(devices.MetricTemperature, 22, start.Add(11*time.Minute)))
+	// late readings of the written bucket and of the skipped one are dropped from the averages
+	store.Record(reading(devices.MetricTemperature, 40, start.Add(2*time.Minute)))
+	store.Record(reading(devices.MetricTemperature, 40, start.Add(6*time.Minute)))
+	store.Record(reading(devices.MetricTemperature, 24, start.Add(12*time.Minute)))
+
+	store.mutex.Lock()
+	for _, tier := range store.tiers {
+		tier.flush(time.Now())
+	}
+	// a bucket is never written twice
+	store.tiers[1].write(Sample{SID: "158d0001f57fee", Metric: devices.MetricTemperature, At: start, Avg: 40, Count: 1})
+	store.mutex.Unlock()
+
+	key := seriesKey{sid: "158d0001f57fee", metric: devices.MetricTemperature}
+	samples, err := store.tiers[1].query(&key, start, start.Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, []Sample{
+		{SID: key.sid, Metric: key.metric, At: start, Avg: 20, Min: 20, Max: 20, Count: 1},
+		{SID: key.sid, Metric: key.metric, At: start.Add(10 * time.Minute), Avg: 23, Min: 22, Max: 24, Count: 2},
+	}, utcSamples(samples))
+
+	samples, err = store.tiers[0].query(&key, start, start.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, samples, 5)
+}
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+	store := New(Config{Dir: dir, RawRetention: 48 * time.Hour, AveragesRetention: 72 * time.Hour})
+	require.NoError(t, store.Init())
+	defer store.Stop()
+
+	now := time.Date(2023, 1, 10, 12, 0, 0, 0, time.UTC)
+	for day := 5; day >= 0; day-- {
+		store.Record(reading(devices.MetricPressure, 101325, now.AddDate(0, 0, -day)))
+	}
+
+	store.mutex.Lock()
+	for _, tier := range store.tiers {
+		tier.flush(time.Now())
+	}
+	store.mutex.Unlock()
+
+	store.prune(now)
+
+	segments := func(tier string) []string {
+		entries, err := os.ReadDir(filepath.Join(dir, tier))
+		require.NoError(t, err)
+
+		names := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+
+		return names
+	}
+
+	require.Equal(t, []string{"2023-01-08.log", "2023-01-09.log", "2023-01-10.log"}, segments("raw"))
+	require.Equal(t, []string{"2023-01-07.log", "2023-01-08.log", "2023-01-09.log", "2023-01-10.log"}, segments("5m"))
+	require.Equal(t, []string{"2023-01.log"}, segments("1h"))
+}
+
+func utcSamples(samples []Sample) []Sample {
+	for idx := range samples {
+		samples[idx].At = samples[idx].At.UTC()
+	}
+
+	return samples
+}
+
+func TestAggregate(t *testing.T) {
+	start := time.Date(2023, 1, 10, 12, 0, 0, 0, time.UTC)
+	samples := []Sample{
+		{At: start.Add(time.Minute), Avg: 20, Min: 19, Max: 21, Count: 1},
+		{At: start.Add(5 * time.Minute), Avg: 24, Min: 22, Max: 26, Count: 3},
+		{At: start.Add(40 * time.Minute), Avg: 18, Min: 18, Max: 18, Count: 1},
+	}
+
+	require.Equal(t, []Sample{
+		{At: start, Avg: 23, Min: 19, Max: 26, Count: 4},
+		{At: start.Add(30 * time.Minute), Avg: 18, Min: 18, Max: 18, Count: 1},
+	}, Aggregate(samples, 15*time.Minute))
+
+	require.Empty(t, Aggregate(nil, 15*time.Minute))
+}
@@ -0,0 +1,106 @@
+package history
+
+import (
+	"time"
+
+	"github.com/cherserver/infocenter/service/devices"
+)
+
+// TrendWindow is the longest window trends are computed over, the recent readings are kept in memory for it.
+const TrendWindow = 3 * time.Hour
+
+type Direction string
+
+const (
+	Rising  Direction = "rising"
+	Falling Direction = "falling"
+	Steady  Direction = "steady"
+)
+
+// steadyRates are the hourly changes of the metrics below which they are steady.
+// The pressure one matches the 1.6 hPa in 3 hours barometric tendency used by weather forecasters.
+var steadyRates = map[devices.Metric]float64{
+	devices.MetricTemperature: 0.2,
+	devices.MetricHumidity:    1,
+	devices.MetricPressure:    160.0 / 3,
+	devices.MetricIlluminance: 10,
+}
+
+// Trend is the direction and rate of a metric change over a time window.
+type Trend struct {
+	Direction   Direction
+	RatePerHour float32
+	Change      float32 // over the window
+}
+
+// TrendOf computes the trend of the samples ordered by time by the least squares line over them.
+// It is unknown when the samples cover less than a half of the window.
+func TrendOf(metric devices.Metric, samples []Sample, window time.Duration) (Trend, bool) {
+	if len(samples) < 2 || samples[len(samples)-1].At.Sub(samples[0].At) < window/2 {
+		return Trend{}, false
+	}
+
+	origin := samples[0].At
+	var sumX, sumY, sumXX, sumXY float64
+	for _, sample := range samples {
+		x := sample.At.Sub(origin).Hours()
+		y := float64(sample.Avg)
+
+		sumX += x
+		sumY += y
+		sumXX += x * x
+		sumXY += x * y
+	}
+
+	count := float64(len(samples))
+	rate := (count*sumXY - sumX*sumY) / (count*sumXX - sumX*sumX)
+
+	direction := Steady
+	if steadyRate, fnd := steadyRates[metric]; !fnd || rate >= steadyRate || rate <= -steadyRate {
+		direction = Rising
+		if rate < 0 {
+			direction = Falling
+		}
+	}
+
+	return Trend{
+		Direction:   direction,
+		RatePerHour: float32(rate),
+		Change:      float32(rate * window.Hours()),
+	}, true
+}
+
+// Trend returns the trend of the device metric over the window up to now, not longer than TrendWindow.
+func (s *Store) Trend(sid string, metric devices.Metric, window time.Duration, now time.Time) (Trend, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	from := now.Add(-window)
+
+	var samples []Sample
+	for _, sample := range s.recent[seriesKey{sid: sid, metric: metric}] {
+		if !sample.At.Before(from) && !sample.At.After(now) {
+			samples = append(samples, sample)
+		}
+	}
+
+	return TrendOf(metric, samples, window)
+}
+
+// remember keeps the sample among the recent ones, forgetting the ones older than TrendWindow.
+func (s *Store) remember(sample Sample) {
+	key := seriesKey{sid: sample.SID, metric: sample.Metric}
+	recent := append(s.recent[key], sample)
+
+	from := sample.At.Add(-TrendWindow)
+	expired := 0
+	for expired < len(recent) && recent[expired].At.Before(from) {
+		expired++
+	}
+
+	if expired > 0 {
+		recent = append(recent[:0:0], recent[expired:]...)
+	}
+
+	s.recent[key] = recent
+}
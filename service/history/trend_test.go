@@ -0,0 +1,74 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cherserver/infocenter/service/devices"
+)
+
+func TestTrendOf(t *testing.T) {
+	start := time.Date(2023, 1, 10, 12, 0, 0, 0, time.UTC)
+	samples := func(values ...float32) []Sample {
+		result := make([]Sample, 0, len(values))
+		for idx, value := range values {
+			result = append(result, Sample{At: start.Add(time.Duration(idx) * 15 * time.Minute), Avg: value})
+		}
+
+		return result
+	}
+
+	trend, fnd := TrendOf(devices.MetricTemperature, samples(20, 20.25, 20.5, 20.75, 21), time.Hour)
+	require.True(t, fnd)
+	require.Equal(t, Rising, trend.Direction)
+	require.InDelta(t, 1, trend.RatePerHour, 0.001)
+	require.InDelta(t, 1, trend.Change, 0.001)
+
+	trend, fnd = TrendOf(devices.MetricTemperature, samples(20, 20.1, 19.9, 20, 20.05), time.Hour)
+	require.True(t, fnd)
+	require.Equal(t, Steady, trend.Direction)
+
+	// 2 hPa fall in 3 hours
+	trend, fnd = TrendOf(devices.MetricPressure,
+		samples(101300, 101275, 101250, 101225, 101200, 101175, 101150, 101125, 101100, 101075, 101050, 101025, 101000),
+		3*time.Hour)
+	require.True(t, fnd)
+	require.Equal(t, Falling, trend.Direction)
+	require.InDelta(t, -300, trend.Change, 0.1)
+
+	_, fnd = TrendOf(devices.MetricTemperature, samples(20, 21), time.Hour)
+	require.False(t, fnd)
+}
+
+func TestStoreTrend(t *testing.T) {
+	dir := t.TempDir()
+	store := New(Config{Dir: dir})
+	require.NoError(t, store.Init())
+
+	now := time.Now().Truncate(time.Minute)
+	for minutes := 240; minutes >= 0; minutes -= 10 {
+		// the humidity rises by 3% an hour
+		store.Record(reading(devices.MetricHumidity, 60-float32(minutes)/20, now.Add(-time.Duration(minutes)*time.Minute)))
+	}
+
+	trend, fnd := store.Trend("158d0001f57fee", devices.MetricHumidity, time.Hour, now)
+	require.True(t, fnd)
+	require.Equal(t, Rising, trend.Direction)
+	require.InDelta(t, 3, trend.RatePerHour, 0.001)
+
+	_, fnd = store.Trend("158d0001f57fee", devices.MetricTemperature, time.Hour, now)
+	require.False(t, fnd)
+
+	store.Stop()
+
+	// the recent readings are restored from the raw ones
+	reopened := New(Config{Dir: dir})
+	require.NoError(t, reopened.Init())
+	defer reopened.Stop()
+
+	trend, fnd = reopened.Trend("158d0001f57fee", devices.MetricHumidity, 3*time.Hour, now)
+	require.True(t, fnd)
+	require.InDelta(t, 9, trend.Change, 0.001)
+}
@@ -10,7 +10,8 @@ type CurrentWeather struct {
 	Gust          float64
 	WindDegree    int
 	WindDir       string
-	Pressure      uint16
+	Pressure      uint16  // sea level, in mmHg
+	PressureMb    float64 // sea level
 	Precipitation float64
 	Humidity      uint8
 	CloudPercent  int
@@ -43,4 +44,5 @@ type ForecastItem struct {
 type Info interface {
 	CurrentWeather() CurrentWeather
 	Forecast() []ForecastItem
+	Available() bool // false when there is no actual weather
 }
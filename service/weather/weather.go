@@ -7,24 +7,39 @@ import (
 	"log"
 	"math"
 	"net/http"
+	"net/url"
+	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/cherserver/infocenter/service/devices"
 )
 
 const (
-	currentAPI  = "https://api.weatherapi.com/v1/current.json?key=f0d704ae336f450ba55152722232907&q=59.891740,30.319351&aqi=no"
-	forecastAPI = "https://api.weatherapi.com/v1/forecast.json?key=f0d704ae336f450ba55152722232907&q=59.891740,30.319351&days=3&aqi=no&alerts=no"
+	currentAPI  = "https://api.weatherapi.com/v1/current.json?key=%s&q=%f,%f&aqi=no"
+	forecastAPI = "https://api.weatherapi.com/v1/forecast.json?key=%s&q=%f,%f&days=3&aqi=no&alerts=no"
 
 	currentWeatherUpdateInterval = 5 * time.Minute
 	forecastUpdateInterval       = 15 * time.Minute
+
+	// the weather is outdated when several updates in a row have failed
+	outdatedWeatherAge = 3 * currentWeatherUpdateInterval
+
+	// SID the current weather readings are reported with.
+	SID = "weather"
+
+	pascalsPerMb = 100
 )
 
 var (
-	_ Info = &Weather{}
+	_ Info                    = &Weather{}
+	_ devices.ReadingNotifier = &Weather{}
 )
 
-func New() *Weather {
+func New(apiKey string, latitude float64, longitude float64) *Weather {
 	weather := &Weather{
+		currentURL:         fmt.Sprintf(currentAPI, url.QueryEscape(apiKey), latitude, longitude),
+		forecastURL:        fmt.Sprintf(forecastAPI, url.QueryEscape(apiKey), latitude, longitude),
 		stopped:            make(chan struct{}),
 		currentWeatherDone: make(chan struct{}),
 		forecastDone:       make(chan struct{}),
@@ -38,14 +53,22 @@ func New() *Weather {
 }
 
 type Weather struct {
+	currentURL  string
+	forecastURL string
+
 	stopped            chan struct{}
 	currentWeatherDone chan struct{}
 	forecastDone       chan struct{}
 
 	currentWeather atomic.Pointer[CurrentWeather]
 	forecast       atomic.Pointer[[]ForecastItem]
+	updatedAt      atomic.Pointer[time.Time]
 
 	conditions map[ConditionCode]Condition
+
+	readingConsumers      []devices.ReadingConsumeFunc
+	readingConsumersMutex sync.Mutex
+	lastObservedAt        time.Time
 }
 
 func (w *Weather) Init() error {
@@ -77,6 +100,21 @@ func (w *Weather) Forecast() []ForecastItem {
 	return *w.forecast.Load()
 }
 
+// Available is false until the current weather is received and when it is not updated for a while.
+func (w *Weather) Available() bool {
+	updatedAt := w.updatedAt.Load()
+	return updatedAt != nil && time.Since(*updatedAt) < outdatedWeatherAge
+}
+
+// RegisterReadingConsumer registers the consumer of the current weather readings, they are reported
+// once per observation of the weather service.
+func (w *Weather) RegisterReadingConsumer(consumeFunc devices.ReadingConsumeFunc) {
+	w.readingConsumersMutex.Lock()
+	defer w.readingConsumersMutex.Unlock()
+
+	w.readingConsumers = append(w.readingConsumers, consumeFunc)
+}
+
 func (w *Weather) workerCurrentWeather() {
 	w.getCurrentWeather()
 
@@ -118,7 +156,7 @@ workerLoop:
 }
 
 func (w *Weather) getCurrentWeather() {
-	data, err := w.performGet(currentAPI)
+	data, err := w.performGet(w.currentURL)
 	if err != nil {
 		log.Printf("failed to get current weather: %v", err)
 		return
@@ -132,11 +170,37 @@ func (w *Weather) getCurrentWeather() {
 	}
 
 	w.currentWeather.Store(w.fillCurrentWeather(&curParsed))
+	updatedAt := time.Now()
+	w.updatedAt.Store(&updatedAt)
 	log.Printf("Current weather received successfully")
+
+	observedAt := time.Unix(int64(curParsed.Current.LastUpdatedEpoch), 0)
+	if observedAt.After(w.lastObservedAt) {
+		w.lastObservedAt = observedAt
+		w.notifyReadings(&curParsed, observedAt)
+	}
+}
+
+func (w *Weather) notifyReadings(response *currentResponse, at time.Time) {
+	w.readingConsumersMutex.Lock()
+	consumers := append([]devices.ReadingConsumeFunc(nil), w.readingConsumers...)
+	w.readingConsumersMutex.Unlock()
+
+	readings := []devices.Reading{
+		{SID: SID, Metric: devices.MetricTemperature, Value: float32(response.Current.TempC), At: at},
+		{SID: SID, Metric: devices.MetricHumidity, Value: float32(response.Current.Humidity), At: at},
+		{SID: SID, Metric: devices.MetricPressure, Value: float32(response.Current.PressureMb * pascalsPerMb), At: at},
+	}
+
+	for _, reading := range readings {
+		for _, consumeFunc := range consumers {
+			consumeFunc(reading)
+		}
+	}
 }
 
 func (w *Weather) getForecast() {
-	data, err := w.performGet(forecastAPI)
+	data, err := w.performGet(w.forecastURL)
 	if err != nil {
 		log.Printf("failed to get current weather: %v", err)
 		return
@@ -205,6 +269,7 @@ func (w *Weather) fillCurrentWeather(response *currentResponse) *CurrentWeather
 		WindDegree:    response.Current.WindDegree,
 		WindDir:       response.Current.WindDir,
 		Pressure:      mBarToMmHg(response.Current.PressureMb),
+		PressureMb:    response.Current.PressureMb,
 		Precipitation: response.Current.PrecipitationMm,
 		Humidity:      uint8(response.Current.Humidity),
 		CloudPercent:  response.Current.Cloud,
@@ -0,0 +1,59 @@
+package weather
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cherserver/infocenter/service/devices"
+)
+
+func TestCurrentWeatherReadings(t *testing.T) {
+	var observedAt atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"current":{"last_updated_epoch":%d,"temp_c":4.5,"is_day":1,`+
+			`"condition":{"code":1000},"pressure_mb":1013,"humidity":81}}`, observedAt.Load())
+	}))
+	defer server.Close()
+
+	weather := New("key", 59.89, 30.31)
+	weather.currentURL = server.URL
+
+	var err error
+	weather.conditions, err = parseConditions()
+	require.NoError(t, err)
+
+	var readings []devices.Reading
+	weather.RegisterReadingConsumer(func(reading devices.Reading) {
+		readings = append(readings, reading)
+	})
+	require.False(t, weather.Available())
+
+	observedAt.Store(1700000100)
+	weather.getCurrentWeather()
+	require.True(t, weather.Available())
+	require.Equal(t, []devices.Reading{
+		{SID: SID, Metric: devices.MetricTemperature, Value: 4.5, At: time.Unix(1700000100, 0)},
+		{SID: SID, Metric: devices.MetricHumidity, Value: 81, At: time.Unix(1700000100, 0)},
+		{SID: SID, Metric: devices.MetricPressure, Value: 101300, At: time.Unix(1700000100, 0)},
+	}, readings)
+
+	// the service observes the weather less often than it is requested
+	weather.getCurrentWeather()
+	require.Len(t, readings, 3)
+
+	observedAt.Store(1700000000)
+	weather.getCurrentWeather()
+	require.Len(t, readings, 3)
+
+	observedAt.Store(1700000400)
+	weather.getCurrentWeather()
+	require.Len(t, readings, 6)
+	require.Equal(t, time.Unix(1700000400, 0), readings[5].At)
+	require.Equal(t, uint16(760), weather.CurrentWeather().Pressure)
+}
@@ -0,0 +1,194 @@
+package weather
+
+import (
+	"math"
+	"time"
+
+	"github.com/cherserver/infocenter/service/devices"
+	"github.com/cherserver/infocenter/service/history"
+)
+
+const (
+	// zambrettiWindow is the period of the pressure change the forecast is based on.
+	zambrettiWindow = 3 * time.Hour
+	// zambrettiTendency is the pressure change in hPa over the window below which the pressure is steady.
+	zambrettiTendency = 1.6
+
+	// the forecaster scale covers the pressure in hPa from 947 to 1050
+	minZambrettiPressure = 947
+	maxZambrettiPressure = 1050
+
+	dayStartHour = 6
+	dayEndHour   = 21
+)
+
+var (
+	_ Info = &Zambretti{}
+)
+
+type zambrettiForecast struct {
+	text      string
+	imageCode string // weather icon of the closest weatherapi.com condition
+}
+
+// zambrettiForecasts are the forecasts of the Negretti & Zambra forecaster by letter.
+var zambrettiForecasts = map[byte]zambrettiForecast{
+	'A': {"Settled fine", "113"},
+	'B': {"Fine weather", "113"},
+	'C': {"Becoming fine", "116"},
+	'D': {"Fine, becoming less settled", "116"},
+	'E': {"Fine, possible showers", "176"},
+	'F': {"Fairly fine, improving", "116"},
+	'G': {"Fairly fine, possible showers early", "176"},
+	'H': {"Fairly fine, showery later", "176"},
+	'I': {"Showery early, improving", "353"},
+	'J': {"Changeable, mending", "119"},
+	'K': {"Fairly fine, showers likely", "176"},
+	'L': {"Rather unsettled clearing later", "119"},
+	'M': {"Unsettled, probably improving", "119"},
+	'N': {"Showery, bright intervals", "353"},
+	'O': {"Showery, becoming less settled", "353"},
+	'P': {"Changeable, some rain", "296"},
+	'Q': {"Unsettled, short fine intervals", "296"},
+	'R': {"Unsettled, rain later", "302"},
+	'S': {"Unsettled, rain at times", "302"},
+	'T': {"Very unsettled, finer at times", "305"},
+	'U': {"Rain at times, worse later", "305"},
+	'V': {"Rain at times, becoming very unsettled", "308"},
+	'W': {"Rain at frequent intervals", "308"},
+	'X': {"Very unsettled, rain", "308"},
+	'Y': {"Stormy, possibly improving", "389"},
+	'Z': {"Stormy, much rain", "389"},
+}
+
+// Letters of the forecaster Z numbers: 1-9 for the falling pressure, 10-19 for steady and 20-32 for rising.
+const (
+	zambrettiFalling = "ABDHORUVX"
+	zambrettiSteady  = "ABEKNPSWXZ"
+	zambrettiRising  = "ABCFGIJLMQTYZ"
+)
+
+// zambrettiLetter returns the forecast letter by the sea level pressure and its change over 3 hours in hPa.
+func zambrettiLetter(pressure float64, change float64) byte {
+	pressure = math.Max(minZambrettiPressure, math.Min(maxZambrettiPressure, pressure))
+
+	var letters string
+	var z, firstZ float64
+	switch {
+	case change <= -zambrettiTendency:
+		letters, firstZ = zambrettiFalling, 1
+		z = 127 - 0.12*pressure
+	case change >= zambrettiTendency:
+		letters, firstZ = zambrettiRising, 20
+		z = 185 - 0.16*pressure
+	default:
+		letters, firstZ = zambrettiSteady, 10
+		z = 144 - 0.13*pressure
+	}
+
+	idx := int(math.Round(z - firstZ))
+	switch {
+	case idx < 0:
+		idx = 0
+	case idx >= len(letters):
+		idx = len(letters) - 1
+	}
+
+	return letters[idx]
+}
+
+// Zambretti forecasts the weather for the next hours by a local barometer pressure and its change,
+// so there is a forecast when the weather service is unreachable. Only the condition and pressure are known.
+type Zambretti struct {
+	registry     *devices.Registry
+	history      *history.Store
+	barometerSID string
+	altitude     float64
+}
+
+// NewZambretti creates the forecaster, the first found barometer is used when barometerSID is empty.
+// The altitude of the barometer is in meters.
+func NewZambretti(registry *devices.Registry, historyStore *history.Store, barometerSID string,
+	altitude float64) *Zambretti {
+	return &Zambretti{
+		registry:     registry,
+		history:      historyStore,
+		barometerSID: barometerSID,
+		altitude:     altitude,
+	}
+}
+
+// Available is false until the barometer pressure change over 3 hours is known.
+func (z *Zambretti) Available() bool {
+	_, _, ok := z.forecast(time.Now())
+	return ok
+}
+
+func (z *Zambretti) CurrentWeather() CurrentWeather {
+	now := time.Now()
+	forecast, pressure, ok := z.forecast(now)
+	if !ok {
+		return CurrentWeather{}
+	}
+
+	return CurrentWeather{
+		IsDay:              now.Hour() >= dayStartHour && now.Hour() < dayEndHour,
+		ConditionText:      forecast.text,
+		ConditionImageCode: forecast.imageCode,
+		Pressure:           mBarToMmHg(pressure),
+		PressureMb:         pressure,
+	}
+}
+
+// Forecast returns the forecast for today and tomorrow, the forecaster doesn't tell them apart.
+func (z *Zambretti) Forecast() []ForecastItem {
+	now := time.Now()
+	forecast, _, ok := z.forecast(now)
+	if !ok {
+		return []ForecastItem{}
+	}
+
+	items := make([]ForecastItem, 0, 2)
+	for day := 0; day < 2; day++ {
+		items = append(items, ForecastItem{
+			Date:               now.AddDate(0, 0, day).Format("2006-01-02"),
+			ConditionText:      forecast.text,
+			ConditionImageCode: forecast.imageCode,
+		})
+	}
+
+	return items
+}
+
+// forecast returns the forecast and the sea level pressure in hPa, false while they are unknown.
+func (z *Zambretti) forecast(now time.Time) (zambrettiForecast, float64, bool) {
+	sid, barometer, ok := z.barometer()
+	if !ok {
+		return zambrettiForecast{}, 0, false
+	}
+
+	trend, ok := z.history.Trend(sid, devices.MetricPressure, zambrettiWindow, now)
+	if !ok {
+		return zambrettiForecast{}, 0, false
+	}
+
+	// the change is of the station pressure, it differs from the sea level one by a fraction of percent
+	pressure := float64(devices.SeaLevelPressure(barometer.Pressure(), z.altitude)) / pascalsPerMb
+	change := float64(trend.Change) / pascalsPerMb
+
+	return zambrettiForecasts[zambrettiLetter(pressure, change)], pressure, true
+}
+
+func (z *Zambretti) barometer() (string, devices.Barometer, bool) {
+	if z.barometerSID != "" {
+		barometer, ok := devices.Lookup[devices.Barometer](z.registry, z.barometerSID)
+		return z.barometerSID, barometer, ok
+	}
+
+	entries := z.registry.WithCapability(devices.CapabilityPressure)
+	if len(entries) == 0 {
+		return "", nil, false
+	}
+
+	return entries[0].Device.SID(), entries[0].Device.(devices.Barometer), true
+}
@@ -0,0 +1,102 @@
+package weather
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cherserver/infocenter/service/devices"
+	"github.com/cherserver/infocenter/service/history"
+)
+
+func TestZambrettiLetter(t *testing.T) {
+	// pressure and change in hPa, the letters are of the Negretti & Zambra forecaster tables
+	cases := []struct {
+		pressure float64
+		change   float64
+		letter   byte
+	}{
+		{pressure: 1050, change: -2, letter: 'A'},
+		{pressure: 1030, change: -2, letter: 'D'},
+		{pressure: 1013, change: -2, letter: 'O'},
+		{pressure: 1000, change: -2, letter: 'U'},
+		{pressure: 985, change: -1.6, letter: 'X'},
+		{pressure: 960, change: -5, letter: 'X'},
+
+		{pressure: 1050, change: 0, letter: 'A'},
+		{pressure: 1030, change: 0, letter: 'A'},
+		{pressure: 1013, change: 1.5, letter: 'E'},
+		{pressure: 1000, change: -1.5, letter: 'N'},
+		{pressure: 985, change: 0, letter: 'S'},
+		{pressure: 960, change: 0, letter: 'Z'},
+
+		{pressure: 1060, change: 2, letter: 'A'},
+		{pressure: 1030, change: 2, letter: 'A'},
+		{pressure: 1020, change: 2, letter: 'C'},
+		{pressure: 1013, change: 1.6, letter: 'F'},
+		{pressure: 1000, change: 2, letter: 'I'},
+		{pressure: 985, change: 2, letter: 'L'},
+		{pressure: 960, change: 5, letter: 'Y'},
+		{pressure: 900, change: 2, letter: 'Z'},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%v/%+v", c.pressure, c.change), func(t *testing.T) {
+			require.Equal(t, string(c.letter), string(zambrettiLetter(c.pressure, c.change)))
+		})
+	}
+
+	for _, letters := range []string{zambrettiFalling, zambrettiSteady, zambrettiRising} {
+		for _, letter := range []byte(letters) {
+			require.Contains(t, zambrettiForecasts, letter)
+		}
+	}
+}
+
+type testBarometer struct {
+	pressure float32
+}
+
+func (d *testBarometer) SID() string             { return "1" }
+func (d *testBarometer) Model() string           { return "weather.v1" }
+func (d *testBarometer) LastUpdateAt() time.Time { return time.Now() }
+func (d *testBarometer) Pressure() float32       { return d.pressure }
+
+func TestZambretti(t *testing.T) {
+	registry := devices.NewRegistry()
+	registry.Add(&testBarometer{pressure: 100000})
+
+	historyStore := history.New(history.Config{Dir: t.TempDir()})
+	require.NoError(t, historyStore.Init())
+	defer historyStore.Stop()
+
+	forecaster := NewZambretti(registry, historyStore, "", 0)
+	require.False(t, forecaster.Available())
+	require.Empty(t, forecaster.Forecast())
+
+	now := time.Now()
+	for minutes := 180; minutes >= 0; minutes -= 10 {
+		historyStore.Record(devices.Reading{
+			SID:    "1",
+			Metric: devices.MetricPressure,
+			Value:  100000 + float32(minutes)*2, // falling by 3.6 hPa
+			At:     now.Add(-time.Duration(minutes) * time.Minute),
+		})
+	}
+
+	require.True(t, forecaster.Available())
+
+	current := forecaster.CurrentWeather()
+	require.Equal(t, "Rain at times, worse later", current.ConditionText)
+	require.Equal(t, "305", current.ConditionImageCode)
+	require.InDelta(t, 1000, current.PressureMb, 0.001)
+	require.Equal(t, uint16(750), current.Pressure)
+
+	forecast := forecaster.Forecast()
+	require.Len(t, forecast, 2)
+	require.Equal(t, now.AddDate(0, 0, 1).Format("2006-01-02"), forecast[1].Date)
+
+	require.False(t, NewZambretti(registry, historyStore, "2", 0).Available())
+}
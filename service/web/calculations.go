@@ -5,6 +5,9 @@ const (
 	minVoltage = 2.82
 
 	voltageScale = maxVoltage - minVoltage
+
+	pascalsPerMmHg = 133.322387415
+	pascalsPerMb   = 100
 )
 
 func batteryLevelFromVoltage(voltage float32) uint8 {
@@ -18,3 +21,7 @@ func batteryLevelFromVoltage(voltage float32) uint8 {
 
 	return uint8(((voltage - minVoltage) / voltageScale) * 100)
 }
+
+func pascalsToMmHg(pressure float32) float32 {
+	return pressure / pascalsPerMmHg
+}
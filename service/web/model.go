@@ -1,39 +1,107 @@
 package web
 
+import (
+	"encoding/json"
+
+	"github.com/cherserver/infocenter/service/devices"
+)
+
 type Sensor struct {
-	SID            string   `json:"sid"`
-	LastUpdateSec  *uint64  `json:"last_update_sec,omitempty"`
-	BatteryPercent *uint8   `json:"battery_percent,omitempty"`
-	Temperature    *float32 `json:"temperature,omitempty"`
-	Humidity       *float32 `json:"humidity,omitempty"`
-	Pressure       *float32 `json:"pressure,omitempty"`
+	SID                  string   `json:"sid"`
+	Model                string   `json:"model"`
+	Capabilities         []string `json:"capabilities"`
+	Name                 string   `json:"name,omitempty"`
+	Room                 string   `json:"room,omitempty"`
+	Icon                 string   `json:"icon,omitempty"`
+	Order                *int     `json:"order,omitempty"`
+	Hidden               bool     `json:"hidden,omitempty"`
+	Gateway              string   `json:"gateway,omitempty"`
+	LastUpdateSec        *uint64  `json:"last_update_sec,omitempty"`
+	Online               *bool    `json:"online,omitempty"`
+	Liveness             string   `json:"liveness,omitempty"`
+	BatteryPercent       *uint8   `json:"battery_percent,omitempty"`
+	Temperature          *float32 `json:"temperature,omitempty"`
+	Humidity             *float32 `json:"humidity,omitempty"`
+	Pressure             *float32 `json:"pressure,omitempty"` // station, in Pascals
+	PressureMmHg         *float32 `json:"pressure_mmhg,omitempty"`
+	SeaLevelPressure     *float32 `json:"sea_level_pressure,omitempty"` // in Pascals
+	SeaLevelPressureMmHg *float32 `json:"sea_level_pressure_mmhg,omitempty"`
+	DewPoint             *float32 `json:"dew_point,omitempty"`
+	AbsoluteHumidity     *float32 `json:"absolute_humidity,omitempty"` // in g/m³
+	HeatIndex            *float32 `json:"heat_index,omitempty"`
+	Humidex              *float32 `json:"humidex,omitempty"`
+	Open                 *bool    `json:"open,omitempty"`
+	LastChangeSec        *uint64  `json:"last_change_sec,omitempty"`
+	OpenSec              *uint64  `json:"open_sec,omitempty"`
+	Occupied             *bool    `json:"occupied,omitempty"`
+	LastMotionSec        *uint64  `json:"last_motion_sec,omitempty"`
+	IdleSec              *uint64  `json:"idle_sec,omitempty"`
+	Illuminance          *float32 `json:"illuminance,omitempty"`
+	Alarm                *bool    `json:"alarm,omitempty"`
+	AlarmChangeSec       *uint64  `json:"alarm_change_sec,omitempty"`
+	SelfTestSec          *uint64  `json:"self_test_sec,omitempty"`
+	Density              *float32 `json:"density,omitempty"`
+	Channels             []bool   `json:"channels,omitempty"`
+	RGB                  string   `json:"rgb,omitempty"`
+	Brightness           *uint8   `json:"brightness,omitempty"`
+	LastEvent            string   `json:"last_event,omitempty"`
+	LastEventSec         *uint64  `json:"last_event_sec,omitempty"`
+
+	Trends map[string]MetricTrends `json:"trends,omitempty"` // by metric
+
+	Raw     *devices.Measurements `json:"raw,omitempty"` // measurements before the calibration
+	RawData json.RawMessage       `json:"raw_data,omitempty"`
+}
+
+type SensorCalibration struct {
+	SID         string               `json:"sid"`
+	Model       string               `json:"model"`
+	Name        string               `json:"name,omitempty"`
+	Calibration devices.Calibration  `json:"calibration"`
+	Raw         devices.Measurements `json:"raw"`
 }
 
 type Weather struct {
 	Current  CurrentWeather `json:"current"`
 	Forecast []ForecastItem `json:"forecast"`
+	Local    bool           `json:"local,omitempty"` // forecast by the local barometer
 }
 
 type CurrentWeather struct {
-	ConditionText  string  `json:"condition_text"`
-	ConditionImage string  `json:"condition_image"`
-	Temperature    float64 `json:"temperature"`
-	Wind           float64 `json:"wind"`
-	Gust           float64 `json:"gust"`
-	WindDegree     int     `json:"wind_degree"`
-	WindDir        string  `json:"wind_dir"`
-	Pressure       uint16  `json:"pressure"`
-	Precipitation  float64 `json:"precipitation"`
-	Humidity       uint8   `json:"humidity"`
-	CloudPercent   int     `json:"cloud_percent"`
-	FeelsLike      float64 `json:"feels_like"`
-	Visibility     float64 `json:"visibility"`
-	UV             float64 `json:"uv"`
+	ConditionText       string  `json:"condition_text"`
+	ConditionImage      string  `json:"condition_image"`
+	Pressure            uint16  `json:"pressure"` // sea level, in mmHg
+	PressurePa          float32 `json:"pressure_pa"`
+	StationPressurePa   float32 `json:"station_pressure_pa"`
+	StationPressureMmHg float32 `json:"station_pressure_mmhg"`
+
+	*WeatherMeasurements // omitted by the local forecast
+
+	Trends map[string]MetricTrends `json:"trends,omitempty"` // by metric
+}
+
+type WeatherMeasurements struct {
+	Temperature   float64 `json:"temperature"`
+	Wind          float64 `json:"wind"`
+	Gust          float64 `json:"gust"`
+	WindDegree    int     `json:"wind_degree"`
+	WindDir       string  `json:"wind_dir"`
+	Precipitation float64 `json:"precipitation"`
+	Humidity      uint8   `json:"humidity"`
+	CloudPercent  int     `json:"cloud_percent"`
+	FeelsLike     float64 `json:"feels_like"`
+	Visibility    float64 `json:"visibility"`
+	UV            float64 `json:"uv"`
 }
 
 type ForecastItem struct {
-	ConditionText      string  `json:"condition_text"`
-	ConditionImage     string  `json:"condition_image"`
+	ConditionText  string `json:"condition_text"`
+	ConditionImage string `json:"condition_image"`
+
+	*ForecastMeasurements // omitted by the local forecast
+}
+
+type ForecastMeasurements struct {
 	MaxTemp            float64 `json:"max_temp"`
 	MinTemp            float64 `json:"min_temp"`
 	AvgTemp            float64 `json:"avg_temp"`
@@ -48,3 +116,31 @@ type ForecastItem struct {
 	DailyChanceOfSnow  int     `json:"daily_chance_of_snow"`
 	UV                 float64 `json:"uv"`
 }
+
+type History struct {
+	SID     string          `json:"sid"`
+	Metric  string          `json:"metric"`
+	From    int64           `json:"from"` // Unix seconds
+	To      int64           `json:"to"`
+	StepSec uint64          `json:"step_sec"`
+	Buckets []HistoryBucket `json:"buckets"`
+}
+
+type HistoryBucket struct {
+	At    int64   `json:"at"`  // start of the bucket, Unix seconds
+	Avg   float32 `json:"avg"` // pressure is at the sea level, in Pascals
+	Min   float32 `json:"min"`
+	Max   float32 `json:"max"`
+	Count int     `json:"count"`
+}
+
+type MetricTrends struct {
+	Hour       *Trend `json:"1h,omitempty"`
+	ThreeHours *Trend `json:"3h,omitempty"`
+}
+
+type Trend struct {
+	Direction   string  `json:"direction"`     // "rising", "falling" or "steady"
+	RatePerHour float32 `json:"rate_per_hour"` // in the metric units
+	Change      float32 `json:"change"`        // over the window
+}
@@ -0,0 +1,158 @@
+package web
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cherserver/infocenter/service/devices"
+)
+
+type sensorFiller func(device devices.Device, sensor *Sensor)
+
+// sensorFillers fill up the sensor fields of every capability, new device types need no changes here
+// unless they bring a new capability.
+var sensorFillers = map[devices.Capability]sensorFiller{
+	devices.CapabilityGatewayChild: func(device devices.Device, sensor *Sensor) {
+		sensor.Gateway = device.(devices.GatewayChild).Gateway()
+	},
+	devices.CapabilityBattery: func(device devices.Device, sensor *Sensor) {
+		val := batteryLevelFromVoltage(device.(devices.BatteryPowered).BatteryVoltage())
+		sensor.BatteryPercent = &val
+	},
+	devices.CapabilityTemperature: func(device devices.Device, sensor *Sensor) {
+		val := device.(devices.Thermometer).Temperature()
+		sensor.Temperature = &val
+	},
+	devices.CapabilityHumidity: func(device devices.Device, sensor *Sensor) {
+		val := device.(devices.Hygrometer).Humidity()
+		sensor.Humidity = &val
+	},
+	devices.CapabilityPressure: func(device devices.Device, sensor *Sensor) {
+		val := device.(devices.Barometer).Pressure()
+		sensor.Pressure = &val
+
+		mmHg := pascalsToMmHg(val)
+		sensor.PressureMmHg = &mmHg
+	},
+	devices.CapabilityComfort: func(device devices.Device, sensor *Sensor) {
+		meter, _ := devices.ComfortMeterOf(device)
+
+		dewPoint := meter.DewPoint()
+		sensor.DewPoint = &dewPoint
+
+		absoluteHumidity := meter.AbsoluteHumidity()
+		sensor.AbsoluteHumidity = &absoluteHumidity
+
+		heatIndex := meter.HeatIndex()
+		sensor.HeatIndex = &heatIndex
+
+		humidex := meter.Humidex()
+		sensor.Humidex = &humidex
+	},
+	devices.CapabilityContact: func(device devices.Device, sensor *Sensor) {
+		dev := device.(devices.ContactSensor)
+
+		val := dev.Open()
+		sensor.Open = &val
+		sensor.LastChangeSec = secondsSince(dev.LastChangeAt())
+
+		if openFor := dev.OpenFor(); openFor > 0 {
+			openSec := uint64(openFor.Seconds())
+			sensor.OpenSec = &openSec
+		}
+	},
+	devices.CapabilityMotion: func(device devices.Device, sensor *Sensor) {
+		dev := device.(devices.MotionSensor)
+
+		val := dev.Occupied()
+		sensor.Occupied = &val
+		sensor.LastMotionSec = secondsSince(dev.LastMotionAt())
+
+		if idleFor := dev.IdleFor(); idleFor > 0 {
+			idleSec := uint64(idleFor.Seconds())
+			sensor.IdleSec = &idleSec
+		}
+	},
+	devices.CapabilityIlluminance: func(device devices.Device, sensor *Sensor) {
+		val := device.(devices.Illuminometer).Illuminance()
+		sensor.Illuminance = &val
+	},
+	devices.CapabilityAlarm: func(device devices.Device, sensor *Sensor) {
+		dev := device.(devices.AlarmSensor)
+
+		val := dev.Alarm()
+		sensor.Alarm = &val
+		sensor.AlarmChangeSec = secondsSince(dev.AlarmChangedAt())
+		sensor.SelfTestSec = secondsSince(dev.LastSelfTestAt())
+	},
+	devices.CapabilityDensity: func(device devices.Device, sensor *Sensor) {
+		val := device.(devices.DensitySensor).Density()
+		sensor.Density = &val
+	},
+	devices.CapabilitySwitch: func(device devices.Device, sensor *Sensor) {
+		dev := device.(devices.Switchable)
+
+		sensor.Channels = make([]bool, dev.Channels())
+		for channel := range sensor.Channels {
+			sensor.Channels[channel] = dev.On(channel)
+		}
+	},
+	devices.CapabilityLight: func(device devices.Device, sensor *Sensor) {
+		dev := device.(devices.RGBLight)
+
+		sensor.RGB = fmt.Sprintf("%06x", dev.RGB())
+
+		val := dev.Brightness()
+		sensor.Brightness = &val
+	},
+	devices.CapabilityEvents: func(device devices.Device, sensor *Sensor) {
+		if event, fnd := device.(devices.EventSource).LastEvent(); fnd {
+			sensor.LastEvent = string(event.Kind)
+			sensor.LastEventSec = secondsSince(event.At)
+		}
+	},
+	devices.CapabilityLiveness: func(device devices.Device, sensor *Sensor) {
+		liveness := device.(devices.Monitored).Liveness()
+
+		online := liveness != devices.Offline
+		sensor.Online = &online
+		sensor.Liveness = liveness.String()
+	},
+	devices.CapabilityCalibration: func(device devices.Device, sensor *Sensor) {
+		val := device.(devices.Calibratable).RawMeasurements()
+		sensor.Raw = &val
+	},
+	devices.CapabilityRaw: func(device devices.Device, sensor *Sensor) {
+		sensor.RawData = device.(devices.RawDevice).RawData()
+	},
+}
+
+func sensorOf(entry devices.Entry) Sensor {
+	sensor := Sensor{
+		SID:           entry.Device.SID(),
+		Model:         entry.Device.Model(),
+		LastUpdateSec: secondsSince(entry.Device.LastUpdateAt()),
+	}
+
+	capabilities := entry.Capabilities.List()
+	sensor.Capabilities = make([]string, 0, len(capabilities))
+	for _, capability := range capabilities {
+		sensor.Capabilities = append(sensor.Capabilities, capability.String())
+
+		if fill, fnd := sensorFillers[capability]; fnd {
+			fill(entry.Device, &sensor)
+		}
+	}
+
+	return sensor
+}
+
+// secondsSince returns nil for the zero time, which means the moment is unknown yet.
+func secondsSince(at time.Time) *uint64 {
+	if at.IsZero() {
+		return nil
+	}
+
+	diff := uint64(time.Now().Sub(at).Seconds())
+	return &diff
+}
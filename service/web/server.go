@@ -8,35 +8,76 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/cherserver/infocenter/service/calibration"
 	"github.com/cherserver/infocenter/service/devices"
+	"github.com/cherserver/infocenter/service/history"
 	"github.com/cherserver/infocenter/service/weather"
 )
 
 const (
 	weatherImgPrefix = "/img/weather/64x64/"
+
+	defaultHistoryPeriod = 24 * time.Hour
+	defaultHistoryStep   = 15 * time.Minute
+	minHistoryStep       = time.Minute
+	maxHistoryBuckets    = 10000
+
+	shortTrendWindow = time.Hour
+	longTrendWindow  = history.TrendWindow
 )
 
+// SensorMeta describes how a device is shown on the dashboard.
+type SensorMeta struct {
+	Name   string
+	Room   string
+	Icon   string
+	Order  *int // nil places the device after the ordered ones
+	Hidden bool
+}
+
 type Server struct {
 	currentSessionId uuid.UUID
 
-	sensors       devices.Sensors
+	listenAddr string
+	rootDir    string
+
+	registry      *devices.Registry
+	sensorsMeta   map[string]SensorMeta
+	calibrations  *calibration.Store
+	history       *history.Store
 	weatherSource weather.Info
+	localWeather  weather.Info
+	altitude      float64
 
 	listener net.Listener
 }
 
-func NewServer(sensors devices.Sensors, weatherSource weather.Info) *Server {
+// NewServer creates the server, sensorsMeta describes devices by SID, altitude of the station is in meters.
+// The optional localWeather is served while weatherSource is unavailable.
+func NewServer(listenAddr string, rootDir string, registry *devices.Registry, sensorsMeta map[string]SensorMeta,
+	calibrations *calibration.Store, historyStore *history.Store, weatherSource weather.Info,
+	localWeather weather.Info, altitude float64) *Server {
 	return &Server{
 		currentSessionId: uuid.New(),
-		sensors:          sensors,
+		listenAddr:       listenAddr,
+		rootDir:          rootDir,
+		registry:         registry,
+		sensorsMeta:      sensorsMeta,
+		calibrations:     calibrations,
+		history:          historyStore,
 		weatherSource:    weatherSource,
+		localWeather:     localWeather,
+		altitude:         altitude,
 	}
 }
 
 func (s *Server) Init() error {
-	fileServer := http.FileServer(http.Dir("./http"))
+	fileServer := http.FileServer(http.Dir(s.rootDir))
 	http.Handle("/", fileServer)
 
 	http.HandleFunc("/reset", s.resetHandler)
@@ -44,7 +85,14 @@ func (s *Server) Init() error {
 	http.HandleFunc("/sensors", s.sensorsHandler)
 	http.HandleFunc("/weather", s.weatherHandler)
 
-	server := &http.Server{Addr: ":80", Handler: nil}
+	http.HandleFunc("/devices/switch", s.switchHandler)
+	http.HandleFunc("/devices/light", s.lightHandler)
+
+	http.HandleFunc("/admin/calibration", s.calibrationHandler)
+
+	http.HandleFunc("/api/history", s.historyHandler)
+
+	server := &http.Server{Addr: s.listenAddr, Handler: nil}
 	var err error
 	s.listener, err = net.Listen("tcp", server.Addr)
 	if err != nil {
@@ -70,28 +118,31 @@ func (s *Server) resetHandler(http.ResponseWriter, *http.Request) {
 	os.Exit(0)
 }
 
+// sensorsHandler lists the devices in the display order, hidden ones are listed only with "all" form value set.
 func (s *Server) sensorsHandler(w http.ResponseWriter, r *http.Request) {
-	_ = r
+	showHidden := r.FormValue("all") != ""
 
-	allDevices := s.sensors.Sensors()
+	entries := s.registry.Entries()
 
-	sensors := make([]Sensor, 0, len(allDevices))
-	for _, data := range allDevices {
-		device, ok := data.(devices.Device)
-		if !ok {
+	sensors := make([]Sensor, 0, len(entries))
+	for _, entry := range entries {
+		sensor := s.describedSensorOf(entry)
+		if sensor.Hidden && !showHidden {
 			continue
 		}
 
-		sensor := Sensor{SID: device.SID()}
-		s.fillUpSensor(data, &sensor)
+		sensors = append(sensors, sensor)
+	}
 
-		if !device.LastUpdateAt().IsZero() {
-			diff := uint64(time.Now().Sub(device.LastUpdateAt()).Seconds())
-			sensor.LastUpdateSec = &diff
+	sort.SliceStable(sensors, func(i, j int) bool {
+		groupI, orderI := s.displayOrder(sensors[i].SID)
+		groupJ, orderJ := s.displayOrder(sensors[j].SID)
+		if groupI != groupJ {
+			return groupI < groupJ
 		}
 
-		sensors = append(sensors, sensor)
-	}
+		return orderI < orderJ
+	})
 
 	statusData, err := json.Marshal(sensors)
 	if err != nil {
@@ -104,34 +155,391 @@ func (s *Server) sensorsHandler(w http.ResponseWriter, r *http.Request) {
 	_, _ = w.Write(statusData)
 }
 
-func (s *Server) fillUpSensor(data interface{}, sensor *Sensor) {
-	if dev, ok := data.(devices.BatteryPowered); ok {
-		val := batteryLevelFromVoltage(dev.BatteryVoltage())
-		sensor.BatteryPercent = &val
+// switchHandler turns a channel of a switchable device on, off or toggles it.
+// Expects POST with "sid", "channel" (defaults to 0) and "state" ("on", "off" or "toggle") form values.
+func (s *Server) switchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sid := r.FormValue("sid")
+	entry, fnd := s.registry.Get(sid)
+	if !fnd {
+		http.Error(w, fmt.Sprintf("device '%v' not found", sid), http.StatusNotFound)
+		return
+	}
+
+	if !entry.Capabilities.Has(devices.CapabilitySwitch) {
+		http.Error(w, fmt.Sprintf("device '%v' is not switchable", sid), http.StatusBadRequest)
+		return
+	}
+	switchable := entry.Device.(devices.Switchable)
+
+	channel := 0
+	if channelValue := r.FormValue("channel"); channelValue != "" {
+		var err error
+		channel, err = strconv.Atoi(channelValue)
+		if err != nil || channel < 0 || channel >= switchable.Channels() {
+			http.Error(w, fmt.Sprintf("invalid channel '%v'", channelValue), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var on bool
+	switch state := r.FormValue("state"); state {
+	case "on":
+		on = true
+	case "off":
+		on = false
+	case "toggle":
+		on = !switchable.On(channel)
+	default:
+		http.Error(w, fmt.Sprintf("invalid state '%v'", state), http.StatusBadRequest)
+		return
+	}
+
+	err := switchable.Switch(channel, on)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to switch: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	s.writeSensor(w, entry)
+}
+
+// lightHandler sets the color and brightness of a RGB light.
+// Expects POST with "sid", "rgb" (hexadecimal color, current one when empty) and "brightness" (0-100) form values.
+func (s *Server) lightHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sid := r.FormValue("sid")
+	entry, fnd := s.registry.Get(sid)
+	if !fnd {
+		http.Error(w, fmt.Sprintf("device '%v' not found", sid), http.StatusNotFound)
+		return
+	}
+
+	if !entry.Capabilities.Has(devices.CapabilityLight) {
+		http.Error(w, fmt.Sprintf("device '%v' has no light", sid), http.StatusBadRequest)
+		return
+	}
+	light := entry.Device.(devices.RGBLight)
+
+	rgb := light.RGB()
+	if rgbValue := strings.TrimPrefix(r.FormValue("rgb"), "#"); rgbValue != "" {
+		parsed, err := strconv.ParseUint(rgbValue, 16, 24)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid rgb '%v'", rgbValue), http.StatusBadRequest)
+			return
+		}
+
+		rgb = uint32(parsed)
+	}
+
+	brightnessValue := r.FormValue("brightness")
+	brightness, err := strconv.ParseUint(brightnessValue, 10, 8)
+	if err != nil || brightness > 100 {
+		http.Error(w, fmt.Sprintf("invalid brightness '%v'", brightnessValue), http.StatusBadRequest)
+		return
+	}
+
+	err = light.SetLight(rgb, uint8(brightness))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to set light: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	s.writeSensor(w, entry)
+}
+
+// calibrationHandler lists the calibrations of the devices with their raw measurements on GET.
+// On POST sets the calibration of a device from a JSON body with "sid" and the calibration fields,
+// the omitted fields are reset, so an empty calibration removes it.
+func (s *Server) calibrationHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		entries := s.registry.WithCapability(devices.CapabilityCalibration)
+
+		calibrations := make([]SensorCalibration, 0, len(entries))
+		for _, entry := range entries {
+			calibrations = append(calibrations, s.sensorCalibrationOf(entry))
+		}
+
+		s.writeJSON(w, calibrations)
+	case http.MethodPost:
+		var request struct {
+			SID string `json:"sid"`
+			devices.Calibration
+		}
+
+		err := json.NewDecoder(r.Body).Decode(&request)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid calibration: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		entry, fnd := s.registry.Get(request.SID)
+		if !fnd {
+			http.Error(w, fmt.Sprintf("device '%v' not found", request.SID), http.StatusNotFound)
+			return
+		}
+
+		if !entry.Capabilities.Has(devices.CapabilityCalibration) {
+			http.Error(w, fmt.Sprintf("device '%v' can't be calibrated", request.SID), http.StatusBadRequest)
+			return
+		}
+
+		err = s.calibrations.Set(request.SID, request.Calibration)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to set calibration: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		s.writeJSON(w, s.sensorCalibrationOf(entry))
+	default:
+		w.Header().Set("Allow", http.MethodGet+", "+http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// isKnownSID tells whether the SID is of a registered or configured device or of the weather.
+func (s *Server) isKnownSID(sid string) bool {
+	if _, fnd := s.registry.Get(sid); fnd || sid == weather.SID {
+		return true
+	}
+
+	_, fnd := s.sensorsMeta[sid]
+	return fnd
+}
+
+// historyHandler returns the device metric readings aggregated into buckets, pressure is at the sea level.
+// Expects "sid" (a device having history, a known one or the weather one) and "metric" form values,
+// optional "from" and "to" (RFC 3339 or Unix seconds, the last day by default) and "step" (duration,
+// 15 minutes by default).
+func (s *Server) historyHandler(w http.ResponseWriter, r *http.Request) {
+	sid := r.FormValue("sid")
+	metric := devices.Metric(r.FormValue("metric"))
+	if !metric.Known() {
+		http.Error(w, fmt.Sprintf("invalid metric '%v'", metric), http.StatusBadRequest)
+		return
+	}
+
+	to, err := parseHistoryTime(r.FormValue("to"), time.Now())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid to: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	from, err := parseHistoryTime(r.FormValue("from"), to.Add(-defaultHistoryPeriod))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid from: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if !from.Before(to) {
+		http.Error(w, "from must be before to", http.StatusBadRequest)
+		return
+	}
+
+	step := defaultHistoryStep
+	if stepValue := r.FormValue("step"); stepValue != "" {
+		step, err = time.ParseDuration(stepValue)
+		if err != nil || step < minHistoryStep {
+			http.Error(w, fmt.Sprintf("invalid step '%v'", stepValue), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if to.Sub(from)/step > maxHistoryBuckets {
+		http.Error(w, fmt.Sprintf("more than %d buckets requested", maxHistoryBuckets), http.StatusBadRequest)
+		return
+	}
+
+	samples, err := s.history.Query(sid, metric, from, to)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to query history: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// removed devices and the ones not reconnected yet after a restart still have their history
+	if len(samples) == 0 && !s.isKnownSID(sid) {
+		http.Error(w, fmt.Sprintf("device '%v' not found", sid), http.StatusNotFound)
+		return
+	}
+
+	buckets := history.Aggregate(samples, step)
+	result := History{
+		SID:     sid,
+		Metric:  string(metric),
+		From:    from.Unix(),
+		To:      to.Unix(),
+		StepSec: uint64(step.Seconds()),
+		Buckets: make([]HistoryBucket, 0, len(buckets)),
+	}
+
+	// barometers record the station pressure, the weather one is at the sea level already
+	seaLevel := func(value float32) float32 { return value }
+	if metric == devices.MetricPressure && sid != weather.SID {
+		seaLevel = func(value float32) float32 { return devices.SeaLevelPressure(value, s.altitude) }
+	}
+
+	for _, bucket := range buckets {
+		result.Buckets = append(result.Buckets, HistoryBucket{
+			At:    bucket.At.Unix(),
+			Avg:   seaLevel(bucket.Avg),
+			Min:   seaLevel(bucket.Min),
+			Max:   seaLevel(bucket.Max),
+			Count: bucket.Count,
+		})
+	}
+
+	s.writeJSON(w, result)
+}
+
+// parseHistoryTime parses RFC 3339 or Unix seconds time, the empty value selects the default one.
+func parseHistoryTime(value string, defaultTime time.Time) (time.Time, error) {
+	if value == "" {
+		return defaultTime, nil
 	}
 
-	if dev, ok := data.(devices.Thermometer); ok {
-		val := dev.Temperature()
-		sensor.Temperature = &val
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
 	}
 
-	if dev, ok := data.(devices.Hygrometer); ok {
-		val := dev.Humidity()
-		sensor.Humidity = &val
+	return time.Parse(time.RFC3339, value)
+}
+
+func (s *Server) sensorCalibrationOf(entry devices.Entry) SensorCalibration {
+	calibratable := entry.Device.(devices.Calibratable)
+
+	return SensorCalibration{
+		SID:         entry.Device.SID(),
+		Model:       entry.Device.Model(),
+		Name:        s.sensorsMeta[entry.Device.SID()].Name,
+		Calibration: calibratable.Calibration(),
+		Raw:         calibratable.RawMeasurements(),
 	}
+}
 
-	if dev, ok := data.(devices.Barometer); ok {
-		val := dev.Pressure()
-		sensor.Pressure = &val
+func (s *Server) writeJSON(w http.ResponseWriter, value any) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to encode response: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+func (s *Server) writeSensor(w http.ResponseWriter, entry devices.Entry) {
+	s.writeJSON(w, s.describedSensorOf(entry))
+}
+
+func (s *Server) describedSensorOf(entry devices.Entry) Sensor {
+	sensor := sensorOf(entry)
+
+	var metrics []devices.Metric
+	if sensor.Temperature != nil {
+		metrics = append(metrics, devices.MetricTemperature)
+	}
+
+	if sensor.Humidity != nil {
+		metrics = append(metrics, devices.MetricHumidity)
+	}
+
+	if sensor.Pressure != nil {
+		metrics = append(metrics, devices.MetricPressure)
+	}
+
+	sensor.Trends = s.trendsOf(sensor.SID, metrics)
+
+	if sensor.Pressure != nil {
+		seaLevel := devices.SeaLevelPressure(*sensor.Pressure, s.altitude)
+		sensor.SeaLevelPressure = &seaLevel
+
+		seaLevelMmHg := pascalsToMmHg(seaLevel)
+		sensor.SeaLevelPressureMmHg = &seaLevelMmHg
+	}
+
+	if meta, fnd := s.sensorsMeta[sensor.SID]; fnd {
+		sensor.Name = meta.Name
+		sensor.Room = meta.Room
+		sensor.Icon = meta.Icon
+		sensor.Order = meta.Order
+		sensor.Hidden = meta.Hidden
+	}
+
+	return sensor
+}
+
+// trendsOf returns the trends of the metrics over the short and long windows, nil when none is known.
+func (s *Server) trendsOf(sid string, metrics []devices.Metric) map[string]MetricTrends {
+	if s.history == nil {
+		return nil
+	}
+
+	now := time.Now()
+	trendOf := func(metric devices.Metric, window time.Duration) *Trend {
+		trend, fnd := s.history.Trend(sid, metric, window, now)
+		if !fnd {
+			return nil
+		}
+
+		return &Trend{
+			Direction:   string(trend.Direction),
+			RatePerHour: trend.RatePerHour,
+			Change:      trend.Change,
+		}
+	}
+
+	var trends map[string]MetricTrends
+	for _, metric := range metrics {
+		metricTrends := MetricTrends{
+			Hour:       trendOf(metric, shortTrendWindow),
+			ThreeHours: trendOf(metric, longTrendWindow),
+		}
+		if metricTrends.Hour == nil && metricTrends.ThreeHours == nil {
+			continue
+		}
+
+		if trends == nil {
+			trends = make(map[string]MetricTrends, len(metrics))
+		}
+		trends[string(metric)] = metricTrends
+	}
+
+	return trends
+}
+
+// displayOrder places the described devices by their order first, then the described ones without order
+// and the others last.
+func (s *Server) displayOrder(sid string) (group int, order int) {
+	meta, fnd := s.sensorsMeta[sid]
+	switch {
+	case fnd && meta.Order != nil:
+		return 0, *meta.Order
+	case fnd:
+		return 1, 0
+	default:
+		return 2, 0
 	}
 }
 
 func (s *Server) weatherHandler(w http.ResponseWriter, r *http.Request) {
 	_ = r
 
+	source, local := s.actualWeatherSource()
 	weatherInfo := &Weather{
-		Current:  s.currentWeather(),
-		Forecast: s.weatherForecast(),
+		Current:  s.currentWeather(source, local),
+		Forecast: s.weatherForecast(source, local),
+		Local:    local,
 	}
 
 	weatherResponse, err := json.Marshal(weatherInfo)
@@ -145,54 +553,91 @@ func (s *Server) weatherHandler(w http.ResponseWriter, r *http.Request) {
 	_, _ = w.Write(weatherResponse)
 }
 
-func (s *Server) currentWeather() CurrentWeather {
-	currWeather := s.weatherSource.CurrentWeather()
+// actualWeatherSource returns the local weather when the weather service is unavailable, true if so.
+func (s *Server) actualWeatherSource() (weather.Info, bool) {
+	if s.weatherSource.Available() || s.localWeather == nil || !s.localWeather.Available() {
+		return s.weatherSource, false
+	}
+
+	return s.localWeather, true
+}
+
+// currentWeather converts the current weather, the local forecast knows only the condition and pressure.
+func (s *Server) currentWeather(source weather.Info, local bool) CurrentWeather {
+	currWeather := source.CurrentWeather()
 
 	phase := "day"
 	if !currWeather.IsDay {
 		phase = "night"
 	}
 
-	return CurrentWeather{
-		ConditionText:  currWeather.ConditionText,
-		ConditionImage: fmt.Sprintf(weatherImgPrefix+"%s/%s.png", phase, currWeather.ConditionImageCode),
-		Temperature:    currWeather.Temperature,
-		Wind:           currWeather.Wind,
-		Gust:           currWeather.Gust,
-		WindDegree:     currWeather.WindDegree,
-		WindDir:        currWeather.WindDir,
-		Pressure:       currWeather.Pressure,
-		Precipitation:  currWeather.Precipitation,
-		Humidity:       currWeather.Humidity,
-		CloudPercent:   currWeather.CloudPercent,
-		FeelsLike:      currWeather.FeelsLike,
-		Visibility:     currWeather.Visibility,
-		UV:             currWeather.UV,
+	pressure := float32(currWeather.PressureMb * pascalsPerMb)
+	station := devices.StationPressure(pressure, s.altitude)
+
+	result := CurrentWeather{
+		ConditionText:       currWeather.ConditionText,
+		ConditionImage:      fmt.Sprintf(weatherImgPrefix+"%s/%s.png", phase, currWeather.ConditionImageCode),
+		Pressure:            currWeather.Pressure,
+		PressurePa:          pressure,
+		StationPressurePa:   station,
+		StationPressureMmHg: pascalsToMmHg(station),
+
+		Trends: s.trendsOf(weather.SID, []devices.Metric{
+			devices.MetricTemperature,
+			devices.MetricHumidity,
+			devices.MetricPressure,
+		}),
 	}
+
+	if !local {
+		result.WeatherMeasurements = &WeatherMeasurements{
+			Temperature:   currWeather.Temperature,
+			Wind:          currWeather.Wind,
+			Gust:          currWeather.Gust,
+			WindDegree:    currWeather.WindDegree,
+			WindDir:       currWeather.WindDir,
+			Precipitation: currWeather.Precipitation,
+			Humidity:      currWeather.Humidity,
+			CloudPercent:  currWeather.CloudPercent,
+			FeelsLike:     currWeather.FeelsLike,
+			Visibility:    currWeather.Visibility,
+			UV:            currWeather.UV,
+		}
+	}
+
+	return result
 }
 
-func (s *Server) weatherForecast() []ForecastItem {
-	forecastData := s.weatherSource.Forecast()
+func (s *Server) weatherForecast(source weather.Info, local bool) []ForecastItem {
+	forecastData := source.Forecast()
 	forecast := make([]ForecastItem, 0, len(forecastData))
 
 	for _, item := range forecastData {
-		forecast = append(forecast, ForecastItem{
-			ConditionText:      item.ConditionText,
-			ConditionImage:     fmt.Sprintf(weatherImgPrefix+"day/%s.png", item.ConditionImageCode),
-			MaxTemp:            item.MaxTemp,
-			MinTemp:            item.MinTemp,
-			AvgTemp:            item.AvgTemp,
-			MaxWind:            item.MaxWind,
-			TotalPrecipitation: item.TotalPrecipitation,
-			TotalSnow:          item.TotalSnow,
-			AvgVis:             item.AvgVis,
-			AvgHumidity:        item.AvgHumidity,
-			DailyWillItRain:    item.DailyWillItRain,
-			DailyChanceOfRain:  item.DailyChanceOfRain,
-			DailyWillItSnow:    item.DailyWillItSnow,
-			DailyChanceOfSnow:  item.DailyChanceOfSnow,
-			UV:                 item.UV,
-		})
+		forecastItem := ForecastItem{
+			ConditionText:  item.ConditionText,
+			ConditionImage: fmt.Sprintf(weatherImgPrefix+"day/%s.png", item.ConditionImageCode),
+		}
+
+		// the local forecast knows only the condition
+		if !local {
+			forecastItem.ForecastMeasurements = &ForecastMeasurements{
+				MaxTemp:            item.MaxTemp,
+				MinTemp:            item.MinTemp,
+				AvgTemp:            item.AvgTemp,
+				MaxWind:            item.MaxWind,
+				TotalPrecipitation: item.TotalPrecipitation,
+				TotalSnow:          item.TotalSnow,
+				AvgVis:             item.AvgVis,
+				AvgHumidity:        item.AvgHumidity,
+				DailyWillItRain:    item.DailyWillItRain,
+				DailyChanceOfRain:  item.DailyChanceOfRain,
+				DailyWillItSnow:    item.DailyWillItSnow,
+				DailyChanceOfSnow:  item.DailyChanceOfSnow,
+				UV:                 item.UV,
+			}
+		}
+
+		forecast = append(forecast, forecastItem)
 	}
 
 	return forecast
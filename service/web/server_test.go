@@ -0,0 +1,373 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cherserver/infocenter/service/calibration"
+	"github.com/cherserver/infocenter/service/devices"
+	"github.com/cherserver/infocenter/service/history"
+	"github.com/cherserver/infocenter/service/weather"
+)
+
+type testThermometer struct {
+	sid string
+}
+
+func (d *testThermometer) SID() string             { return d.sid }
+func (d *testThermometer) Model() string           { return "sensor_ht" }
+func (d *testThermometer) LastUpdateAt() time.Time { return time.Now() }
+func (d *testThermometer) Temperature() float32    { return 21.5 }
+
+type testCalibratableThermometer struct {
+	testThermometer
+	calibration devices.Calibration
+}
+
+func (d *testCalibratableThermometer) Temperature() float32 {
+	return d.calibration.Temperature(d.testThermometer.Temperature())
+}
+
+func (d *testCalibratableThermometer) Calibration() devices.Calibration     { return d.calibration }
+func (d *testCalibratableThermometer) SetCalibration(c devices.Calibration) { d.calibration = c }
+
+func (d *testCalibratableThermometer) RawMeasurements() devices.Measurements {
+	raw := d.testThermometer.Temperature()
+	return devices.Measurements{Temperature: &raw}
+}
+
+type testSwitch struct {
+	sid      string
+	channels []bool
+}
+
+func (d *testSwitch) SID() string             { return d.sid }
+func (d *testSwitch) Model() string           { return "ctrl_neutral2" }
+func (d *testSwitch) LastUpdateAt() time.Time { return time.Now() }
+func (d *testSwitch) Channels() int           { return len(d.channels) }
+func (d *testSwitch) On(channel int) bool     { return d.channels[channel] }
+
+func (d *testSwitch) Switch(channel int, on bool) error {
+	d.channels[channel] = on
+	return nil
+}
+
+type testWeather struct {
+	available bool
+	condition string
+}
+
+func (w *testWeather) CurrentWeather() weather.CurrentWeather {
+	return weather.CurrentWeather{ConditionText: w.condition}
+}
+
+func (w *testWeather) Forecast() []weather.ForecastItem {
+	return []weather.ForecastItem{{ConditionText: w.condition}}
+}
+
+func (w *testWeather) Available() bool { return w.available }
+
+func TestSensorsHandler(t *testing.T) {
+	registry := devices.NewRegistry()
+	registry.Add(&testThermometer{sid: "1"})
+	registry.Add(&testThermometer{sid: "2"})
+	registry.Add(&testThermometer{sid: "3"})
+	registry.Add(&testThermometer{sid: "4"})
+	registry.Add(&testThermometer{sid: "5"})
+
+	first, second := 1, 2
+	server := NewServer(":0", ".", registry, map[string]SensorMeta{
+		"2": {Name: "Kitchen", Room: "kitchen", Order: &second},
+		"3": {Name: "Bedroom", Order: &first},
+		"4": {Hidden: true},
+		"5": {Name: "Hall"},
+	}, nil, nil, nil, nil, 0)
+
+	getSensors := func(target string) []Sensor {
+		recorder := httptest.NewRecorder()
+		server.sensorsHandler(recorder, httptest.NewRequest("GET", target, nil))
+
+		var sensors []Sensor
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &sensors))
+		return sensors
+	}
+
+	sensors := getSensors("/sensors")
+	require.Len(t, sensors, 4)
+	require.Equal(t, "3", sensors[0].SID)
+	require.Equal(t, "Bedroom", sensors[0].Name)
+	require.Equal(t, "2", sensors[1].SID)
+	require.Equal(t, "kitchen", sensors[1].Room)
+	require.Equal(t, "5", sensors[2].SID)
+	require.Nil(t, sensors[2].Order)
+	require.Equal(t, "1", sensors[3].SID)
+	require.Equal(t, []string{"temperature"}, sensors[3].Capabilities)
+
+	sensors = getSensors("/sensors?all=1")
+	require.Len(t, sensors, 5)
+	require.Equal(t, "4", sensors[2].SID)
+	require.True(t, sensors[2].Hidden)
+	require.Equal(t, "5", sensors[3].SID)
+}
+
+func TestSwitchHandler(t *testing.T) {
+	registry := devices.NewRegistry()
+	registry.Add(&testThermometer{sid: "1"})
+	relay := &testSwitch{sid: "2", channels: []bool{false, true}}
+	registry.Add(relay)
+
+	server := NewServer(":0", ".", registry, nil, nil, nil, nil, nil, 0)
+
+	postSwitch := func(form string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest("POST", "/devices/switch", strings.NewReader(form))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		recorder := httptest.NewRecorder()
+		server.switchHandler(recorder, request)
+		return recorder
+	}
+
+	recorder := httptest.NewRecorder()
+	server.switchHandler(recorder, httptest.NewRequest("GET", "/devices/switch?sid=2&state=on", nil))
+	require.Equal(t, 405, recorder.Code)
+
+	require.Equal(t, 404, postSwitch("sid=3&state=on").Code)
+	require.Equal(t, 400, postSwitch("sid=1&state=on").Code)
+	require.Equal(t, 400, postSwitch("sid=2&state=on&channel=2").Code)
+	require.Equal(t, 400, postSwitch("sid=2&state=on&channel=-1").Code)
+	require.Equal(t, 400, postSwitch("sid=2&state=on&channel=first").Code)
+	require.Equal(t, 400, postSwitch("sid=2&state=dim").Code)
+	require.Equal(t, []bool{false, true}, relay.channels)
+
+	recorder = postSwitch("sid=2&state=on")
+	require.Equal(t, 200, recorder.Code)
+	require.Equal(t, []bool{true, true}, relay.channels)
+
+	var sensor Sensor
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &sensor))
+	require.Equal(t, []bool{true, true}, sensor.Channels)
+
+	require.Equal(t, 200, postSwitch("sid=2&state=toggle&channel=1").Code)
+	require.Equal(t, []bool{true, false}, relay.channels)
+
+	require.Equal(t, 200, postSwitch("sid=2&state=toggle&channel=1").Code)
+	require.Equal(t, []bool{true, true}, relay.channels)
+
+	require.Equal(t, 200, postSwitch("sid=2&state=off&channel=0").Code)
+	require.Equal(t, []bool{false, true}, relay.channels)
+}
+
+func TestCalibrationHandler(t *testing.T) {
+	registry := devices.NewRegistry()
+	registry.Add(&testThermometer{sid: "1"})
+	registry.Add(&testCalibratableThermometer{testThermometer: testThermometer{sid: "2"}})
+
+	path := filepath.Join(t.TempDir(), "calibration.json")
+	store, err := calibration.Load(path, registry)
+	require.NoError(t, err)
+
+	server := NewServer(":0", ".", registry, nil, store, nil, nil, nil, 0)
+
+	setCalibration := func(body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		server.calibrationHandler(recorder, httptest.NewRequest("POST", "/admin/calibration", strings.NewReader(body)))
+		return recorder
+	}
+
+	require.Equal(t, 400, setCalibration(`{"sid": "1", "temperature_offset": -0.5}`).Code)
+	require.Equal(t, 404, setCalibration(`{"sid": "5", "temperature_offset": -0.5}`).Code)
+
+	recorder := setCalibration(`{"sid": "2", "temperature_offset": -0.5}`)
+	require.Equal(t, 200, recorder.Code)
+
+	var sensorCalibration SensorCalibration
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &sensorCalibration))
+	require.Equal(t, float32(-0.5), sensorCalibration.Calibration.TemperatureOffset)
+	require.Equal(t, float32(21.5), *sensorCalibration.Raw.Temperature)
+
+	sensor, fnd := devices.Lookup[devices.Thermometer](registry, "2")
+	require.True(t, fnd)
+	require.Equal(t, float32(21), sensor.Temperature())
+
+	recorder = httptest.NewRecorder()
+	server.calibrationHandler(recorder, httptest.NewRequest("GET", "/admin/calibration", nil))
+
+	var calibrations []SensorCalibration
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &calibrations))
+	require.Len(t, calibrations, 1)
+	require.Equal(t, "2", calibrations[0].SID)
+
+	reloaded, err := calibration.Load(path, devices.NewRegistry())
+	require.NoError(t, err)
+
+	stored, fnd := reloaded.Get("2")
+	require.True(t, fnd)
+	require.Equal(t, float32(-0.5), stored.TemperatureOffset)
+}
+
+func TestHistoryHandler(t *testing.T) {
+	registry := devices.NewRegistry()
+	registry.Add(&testThermometer{sid: "1"})
+
+	historyStore := history.New(history.Config{Dir: t.TempDir()})
+	require.NoError(t, historyStore.Init())
+	defer historyStore.Stop()
+
+	start := time.Now().Add(-2 * time.Hour).Truncate(time.Hour)
+	for minute, value := range []float32{20, 22, 21, 24} {
+		historyStore.Record(devices.Reading{
+			SID:    "1",
+			Metric: devices.MetricTemperature,
+			Value:  value,
+			At:     start.Add(time.Duration(minute*10) * time.Minute),
+		})
+	}
+
+	// the device is removed or not reconnected yet
+	historyStore.Record(devices.Reading{SID: "3", Metric: devices.MetricTemperature, Value: 18, At: start})
+
+	server := NewServer(":0", ".", registry, map[string]SensorMeta{"4": {Name: "Attic"}}, nil, historyStore,
+		nil, nil, 0)
+
+	getHistory := func(target string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		server.historyHandler(recorder, httptest.NewRequest("GET", target, nil))
+		return recorder
+	}
+
+	require.Equal(t, 404, getHistory("/api/history?sid=2&metric=temperature").Code)
+	require.Equal(t, 200, getHistory(fmt.Sprintf("/api/history?sid=3&metric=temperature&from=%d", start.Unix())).Code)
+	require.Equal(t, 200, getHistory("/api/history?sid=4&metric=temperature").Code)
+	require.Equal(t, 200, getHistory("/api/history?sid=weather&metric=pressure").Code)
+	require.Equal(t, 400, getHistory("/api/history?sid=1&metric=voltage").Code)
+	require.Equal(t, 400, getHistory("/api/history?sid=1&metric=temperature&step=1s").Code)
+
+	recorder := getHistory(fmt.Sprintf("/api/history?sid=1&metric=temperature&from=%d&step=20m", start.Unix()))
+	require.Equal(t, 200, recorder.Code)
+
+	var result History
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &result))
+	require.Equal(t, uint64(1200), result.StepSec)
+	require.Equal(t, []HistoryBucket{
+		{At: start.Unix(), Avg: 21, Min: 20, Max: 22, Count: 2},
+		{At: start.Add(20 * time.Minute).Unix(), Avg: 22.5, Min: 21, Max: 24, Count: 2},
+	}, result.Buckets)
+}
+
+func TestPressureHistory(t *testing.T) {
+	registry := devices.NewRegistry()
+	registry.Add(&testThermometer{sid: "1"})
+
+	historyStore := history.New(history.Config{Dir: t.TempDir()})
+	require.NoError(t, historyStore.Init())
+	defer historyStore.Stop()
+
+	at := time.Now().Add(-time.Hour)
+	historyStore.Record(devices.Reading{SID: "1", Metric: devices.MetricPressure, Value: 100000, At: at})
+	historyStore.Record(devices.Reading{SID: weather.SID, Metric: devices.MetricPressure, Value: 101300, At: at})
+
+	server := NewServer(":0", ".", registry, nil, nil, historyStore, nil, nil, 150)
+
+	getBucket := func(sid string) HistoryBucket {
+		recorder := httptest.NewRecorder()
+		server.historyHandler(recorder, httptest.NewRequest("GET", "/api/history?metric=pressure&sid="+sid, nil))
+		require.Equal(t, 200, recorder.Code)
+
+		var result History
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &result))
+		require.Len(t, result.Buckets, 1)
+		return result.Buckets[0]
+	}
+
+	// the barometer pressure is reduced to the sea level as the weather one
+	bucket := getBucket("1")
+	require.InDelta(t, 101797, bucket.Avg, 1)
+	require.Equal(t, bucket.Avg, bucket.Min)
+	require.Equal(t, bucket.Avg, bucket.Max)
+
+	require.Equal(t, float32(101300), getBucket(weather.SID).Avg)
+}
+
+func TestSensorTrends(t *testing.T) {
+	registry := devices.NewRegistry()
+	entry := registry.Add(&testThermometer{sid: "1"})
+
+	historyStore := history.New(history.Config{Dir: t.TempDir()})
+	require.NoError(t, historyStore.Init())
+	defer historyStore.Stop()
+
+	now := time.Now()
+	for minutes := 60; minutes >= 0; minutes -= 10 {
+		historyStore.Record(devices.Reading{
+			SID:    "1",
+			Metric: devices.MetricTemperature,
+			Value:  21.5 - float32(minutes)/60,
+			At:     now.Add(-time.Duration(minutes) * time.Minute),
+		})
+	}
+
+	server := NewServer(":0", ".", registry, nil, nil, historyStore, nil, nil, 0)
+
+	sensor := server.describedSensorOf(entry)
+	require.NotNil(t, sensor.Trends["temperature"].Hour)
+	require.Equal(t, "rising", sensor.Trends["temperature"].Hour.Direction)
+	require.InDelta(t, 1, sensor.Trends["temperature"].Hour.RatePerHour, 0.001)
+	require.Nil(t, sensor.Trends["temperature"].ThreeHours)
+}
+
+func TestWeatherFallback(t *testing.T) {
+	remote := &testWeather{condition: "Sunny"}
+	local := &testWeather{condition: "Settled fine"}
+
+	getRawWeather := func(server *Server) []byte {
+		recorder := httptest.NewRecorder()
+		server.weatherHandler(recorder, httptest.NewRequest("GET", "/weather", nil))
+		return recorder.Body.Bytes()
+	}
+
+	getWeather := func(server *Server) Weather {
+		var result Weather
+		require.NoError(t, json.Unmarshal(getRawWeather(server), &result))
+		return result
+	}
+
+	server := NewServer(":0", ".", devices.NewRegistry(), nil, nil, nil, remote, nil, 0)
+	require.False(t, getWeather(server).Local)
+
+	server = NewServer(":0", ".", devices.NewRegistry(), nil, nil, nil, remote, local, 0)
+	result := getWeather(server)
+	require.Equal(t, "Sunny", result.Current.ConditionText)
+	require.NotNil(t, result.Current.WeatherMeasurements)
+	require.NotNil(t, result.Forecast[0].ForecastMeasurements)
+
+	remote.available = false
+	local.available = true
+	result = getWeather(server)
+	require.True(t, result.Local)
+	require.Equal(t, "Settled fine", result.Current.ConditionText)
+	require.Equal(t, "Settled fine", result.Forecast[0].ConditionText)
+
+	// the values the local forecast doesn't know are omitted instead of being zero
+	var raw struct {
+		Current  map[string]json.RawMessage   `json:"current"`
+		Forecast []map[string]json.RawMessage `json:"forecast"`
+	}
+	require.NoError(t, json.Unmarshal(getRawWeather(server), &raw))
+	require.Contains(t, raw.Current, "pressure")
+	for _, field := range []string{"temperature", "humidity", "wind", "wind_dir", "precipitation", "uv"} {
+		require.NotContains(t, raw.Current, field)
+	}
+	for _, field := range []string{"max_temp", "min_temp", "avg_temp", "avg_humidity", "daily_chance_of_rain"} {
+		require.NotContains(t, raw.Forecast[0], field)
+	}
+
+	remote.available = true
+	require.Equal(t, "Sunny", getWeather(server).Current.ConditionText)
+}